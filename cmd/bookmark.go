@@ -16,13 +16,16 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
-	"runtime"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/henrikac/bookmark/internal/history"
+	"github.com/henrikac/bookmark/internal/search"
 	"github.com/henrikac/bookmark/internal/store"
 	"github.com/spf13/cobra"
 )
@@ -32,59 +35,311 @@ import (
 
 var (
 	bookmarkStore     = store.NewBookmarkFileStore()
-	bookmarkAddCmd    = BookmarkAddCmd(bookmarkStore)
-	bookmarkExecCmd   = BookmarkExecCmd(bookmarkStore)
+	bookmarkAddCmd    = BookmarkAddCmd(bookmarkStore, prompter)
+	bookmarkEditCmd   = BookmarkEditCmd(bookmarkStore)
+	bookmarkExecCmd   = BookmarkExecCmd(bookmarkStore, prompter)
 	bookmarkListCmd   = BookmarkListCmd(bookmarkStore)
-	bookmarkRemoveCmd = BookmarkRemoveCmd(bookmarkStore)
+	bookmarkRemoveCmd = BookmarkRemoveCmd(bookmarkStore, prompter)
 	bookmarkSearchCmd = BookmarkSearchCmd(bookmarkStore)
 )
 
 // BookmarkAddCmd initializes a new add command.
-func BookmarkAddCmd(bs store.BookmarkStoreLoadUpdater) *cobra.Command {
-	return &cobra.Command{
+func BookmarkAddCmd(bs store.BookmarkStore, p Prompter) *cobra.Command {
+	var opts addOptions
+	addCmd := &cobra.Command{
 		Use:   "add",
 		Short: "Add a new bookmark",
-		Args:  cobra.MinimumNArgs(2),
+		Long: `Add a new bookmark.
+
+With --script the bookmark is a multi-line script, kept in its own
+executable file next to the store. The script is written in your editor,
+taken from VISUAL or EDITOR, or read from the input if it is not a
+terminal. It must start with a shebang line naming its interpreter, e.g.
+#!/usr/bin/env bash.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if opts.script {
+				return cobra.ExactArgs(1)(cmd, args)
+			}
+			return cobra.MinimumNArgs(2)(cmd, args)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.script {
+				return addScript(cmd, bs, p, args[0], opts)
+			}
+			return addBookmark(cmd, bs, p, args[0], strings.Join(args[1:], " "), opts)
+		},
+	}
+	addFlags(addCmd, bs, &opts)
+	addCmd.Flags().BoolVar(&opts.script, "script", false, "add a multi-line script written in your editor or read from the input")
+	addCmd.MarkFlagsMutuallyExclusive("script", "shell")
+	return addCmd
+}
+
+// addOptions are the flags of the add command.
+type addOptions struct {
+	noValidate bool
+	script     bool
+	shell, cwd string
+	env, tags  []string
+}
+
+// addFlags adds the flags of the add command to c.
+func addFlags(c *cobra.Command, bs store.BookmarkStoreLoader, opts *addOptions) {
+	c.Flags().BoolVar(&opts.noValidate, "no-validate", false, "add the command without checking its syntax")
+	c.Flags().StringVar(&opts.shell, "shell", "", "the shell that runs the command: "+strings.Join(shellNames(), ", "))
+	c.Flags().StringVar(&opts.cwd, "cwd", "", "the directory the command is run in")
+	c.Flags().StringArrayVar(&opts.env, "env", nil, "set an environment variable for the command (KEY=VALUE)")
+	c.Flags().StringArrayVar(&opts.tags, "tag", nil, "tag the bookmark, can be given multiple times")
+	_ = c.RegisterFlagCompletionFunc("shell", completeShells)
+	_ = c.RegisterFlagCompletionFunc("tag", completeTags(bs))
+}
+
+// addBookmark saves command as a bookmark with the given name. The user is
+// asked with p before an existing bookmark is overridden.
+func addBookmark(cmd *cobra.Command, bs store.BookmarkStore, p Prompter, name, bookmarkCmd string, opts addOptions) error {
+	if opts.shell != "" {
+		if err := validateShellName(opts.shell); err != nil {
+			return err
+		}
+	}
+	if !opts.noValidate {
+		if err := validateCommand(cmd, bookmarkCmd, resolveShell(opts.shell)); err != nil {
+			return err
+		}
+	}
+	vars, err := parseKeyValues(opts.env)
+	if err != nil {
+		return err
+	}
+	dir, err := absPath(opts.cwd)
+	if err != nil {
+		return err
+	}
+	if err := validateTags(opts.tags); err != nil {
+		return err
+	}
+	bookmarks, err := bs.Load()
+	if err != nil {
+		return err
+	}
+	val, found := bookmarks[name]
+	if found {
+		cmd.Printf("%s already exists: %s\n", name, val.Summary())
+		override, err := p.Confirm(cmd, "Do you want to override it")
+		if err != nil || !override {
+			return err
+		}
+	}
+	return saveBookmark(cmd, bs, name, found, func(bookmark *store.Bookmark) error {
+		bookmark.Kind = store.KindCommand
+		bookmark.Command = bookmarkCmd
+		bookmark.Target = ""
+		if cmd.Flags().Changed("shell") {
+			bookmark.Shell = opts.shell
+		}
+		if cmd.Flags().Changed("cwd") {
+			bookmark.Cwd = dir
+		}
+		if cmd.Flags().Changed("env") {
+			bookmark.Env = vars
+		}
+		if cmd.Flags().Changed("tag") {
+			bookmark.Tags = mergeTags(nil, opts.tags)
+		}
+		return nil
+	})
+}
+
+// saveBookmark changes the bookmark with the given name with set, adding it
+// if it does not exist, and tells the user whether it was added or updated.
+// found reports whether the bookmark existed when the user was asked to
+// override it. The script of a replaced script bookmark is removed, and so
+// is a script written by set if the bookmark cannot be saved.
+func saveBookmark(cmd *cobra.Command, bs store.BookmarkStore, name string, found bool, set func(*store.Bookmark) error) error {
+	var unused, added []string
+	err := bs.Modify(func(bookmarks store.BookmarkContainer) error {
+		bookmark, exists := bookmarks[name]
+		if exists && !found {
+			return fmt.Errorf("bookmark \"%s\" was added while you were adding it", name)
+		}
+		if !exists {
+			bookmark.CreatedAt = time.Now()
+		}
+		old := bookmark
+		if err := set(&bookmark); err != nil {
+			return err
+		}
+		added = addedScripts(old, bookmark)
+		bookmarks[name] = bookmark
+		unused = unusedScripts(bookmarks, old)
+		return nil
+	})
+	if err != nil {
+		_ = removeScripts(bs, added)
+		return err
+	}
+	if err := removeScripts(bs, unused); err != nil {
+		return err
+	}
+	if found {
+		cmd.Printf("Bookmark \"%s\" has been updated successfully!\n", name)
+	} else {
+		cmd.Printf("New bookmark \"%s\" has been added successfully!\n", name)
+	}
+	return nil
+}
+
+// addTarget saves target as a bookmark of the given kind with the given
+// name. The user is asked with p before an existing bookmark is
+// overridden.
+func addTarget(cmd *cobra.Command, bs store.BookmarkStore, p Prompter, name string, kind store.Kind, target string, tags []string) error {
+	if err := validateTags(tags); err != nil {
+		return err
+	}
+	bookmarks, err := bs.Load()
+	if err != nil {
+		return err
+	}
+	val, found := bookmarks[name]
+	if found {
+		cmd.Printf("%s already exists: %s\n", name, val.Summary())
+		override, err := p.Confirm(cmd, "Do you want to override it")
+		if err != nil || !override {
+			return err
+		}
+	}
+	return saveBookmark(cmd, bs, name, found, func(bookmark *store.Bookmark) error {
+		bookmark.Kind = kind
+		bookmark.Target = target
+		bookmark.Command = ""
+		bookmark.Shell = ""
+		bookmark.Cwd = ""
+		bookmark.Env = nil
+		if cmd.Flags().Changed("tag") {
+			bookmark.Tags = mergeTags(nil, tags)
+		}
+		return nil
+	})
+}
+
+// BookmarkEditCmd initializes a new edit command.
+func BookmarkEditCmd(bs store.BookmarkStore) *cobra.Command {
+	var cwd, format string
+	var env, unsetEnv []string
+	var all bool
+	editCmd := &cobra.Command{
+		Use:   "edit <bookmark>",
+		Short: "Edit a bookmark",
+		Long: `Edit a bookmark.
+
+Without flags the bookmark is opened in your editor, taken from VISUAL or
+EDITOR, as YAML or TOML. If the edited bookmark is invalid, the editor is
+opened again with the errors shown at the top of the file. With --all every
+bookmark is edited at once. For a script bookmark the script itself is
+opened in your editor.
+
+The working directory and environment of a bookmark can also be changed
+with --cwd, --env and --unset-env.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if all {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
+		ValidArgsFunction: completeBookmarks(bs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "yaml" && format != "toml" {
+				return fmt.Errorf("unknown format \"%s\", expected one of: %s", format, strings.Join(editFormats, ", "))
+			}
+			if all {
+				return editAllBookmarks(cmd, bs, format)
+			}
 			name := args[0]
-			bookmarkCmd := strings.Join(args[1:], " ")
-			bookmarks, err := bs.Load()
+			if !cmd.Flags().Changed("cwd") && !cmd.Flags().Changed("env") && !cmd.Flags().Changed("unset-env") {
+				return editBookmark(cmd, bs, name, format)
+			}
+			vars, err := parseKeyValues(env)
 			if err != nil {
 				return err
 			}
-			if val, found := bookmarks[name]; found {
-				cmd.Printf("%s already exists: %s\n", name, val)
-				var input string
-				cmd.Printf("Do you want to override it (y/N)? ")
-				_, _ = fmt.Scanln(&input)
-				if strings.ToLower(strings.TrimSpace(input)) == "y" {
-					bookmarks[name] = bookmarkCmd
-					err := bs.Update(bookmarks)
-					if err != nil {
-						return err
+			dir, err := absPath(cwd)
+			if err != nil {
+				return err
+			}
+			found := true
+			err = bs.Modify(func(bookmarks store.BookmarkContainer) error {
+				bookmark, ok := bookmarks[name]
+				if !ok {
+					found = false
+					return nil
+				}
+				if cmd.Flags().Changed("cwd") {
+					bookmark.Cwd = dir
+				}
+				for k, v := range vars {
+					if bookmark.Env == nil {
+						bookmark.Env = make(map[string]string)
 					}
-					cmd.Printf("Bookmark \"%s\" has been updated successfully!\n", name)
+					bookmark.Env[k] = v
+				}
+				for _, k := range unsetEnv {
+					delete(bookmark.Env, k)
 				}
+				if len(bookmark.Env) == 0 {
+					bookmark.Env = nil
+				}
+				bookmarks[name] = bookmark
 				return nil
-			}
-			bookmarks[name] = bookmarkCmd
-			err = bs.Update(bookmarks)
+			})
 			if err != nil {
 				return err
 			}
-			cmd.Printf("New bookmark \"%s\" has been added successfully!\n", name)
+			if !found {
+				cmd.Printf("Unable to find bookmark: \"%s\"\n", name)
+				return nil
+			}
+			cmd.Printf("Bookmark \"%s\" has been updated successfully!\n", name)
 			return nil
 		},
 	}
+	editCmd.Flags().StringVar(&cwd, "cwd", "", "the directory the command is run in, empty to use the current directory")
+	editCmd.Flags().StringArrayVar(&env, "env", nil, "set an environment variable for the command (KEY=VALUE)")
+	editCmd.Flags().StringArrayVar(&unsetEnv, "unset-env", nil, "remove an environment variable from the bookmark")
+	editCmd.Flags().StringVar(&format, "format", "yaml", "the format the bookmark is edited in: "+strings.Join(editFormats, ", "))
+	editCmd.Flags().BoolVar(&all, "all", false, "edit every bookmark at once")
+	editCmd.MarkFlagsMutuallyExclusive("all", "cwd")
+	editCmd.MarkFlagsMutuallyExclusive("all", "env")
+	editCmd.MarkFlagsMutuallyExclusive("all", "unset-env")
+	_ = editCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(editFormats, cobra.ShellCompDirectiveNoFileComp))
+	return editCmd
 }
 
 // BookmarkExecCmd initializes a new exec command.
-func BookmarkExecCmd(bs store.BookmarkStoreLoader) *cobra.Command {
-	return &cobra.Command{
-		Use:   "exec",
+func BookmarkExecCmd(bs store.BookmarkStore, p Prompter) *cobra.Command {
+	var opts execOptions
+	execCmd := &cobra.Command{
+		Use:   "exec [bookmark] [placeholder values...] [-- args...]",
 		Short: "Execute a bookmark",
-		Args:  cobra.ExactArgs(1),
+		Long: `Execute a bookmark.
+
+Arguments given after "--" are passed on to the bookmarked command. They are
+available as $@ inside the command or, if the command does not refer to any
+positional parameters, appended to it. The bookmark must be named before
+"--".
+
+If no bookmark is given, a picker is shown to choose one.`,
+		ValidArgsFunction: completeExecArgs(bs, store.KindCommand, store.KindScript, store.KindURL, store.KindFile),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.ArgsLenAtDash() == 0 && len(args) > 0 {
+				return fmt.Errorf("the bookmark must be named before \"--\", e.g. bookmark exec <bookmark> -- %s", strings.Join(args, " "))
+			}
+			if len(args) == 0 {
+				name, ok, err := pickBookmark(cmd, bs, p, "")
+				if err != nil || !ok {
+					return err
+				}
+				args = []string{name}
+			}
 			bookmarks, err := bs.Load()
 			if err != nil {
 				return err
@@ -94,63 +349,184 @@ func BookmarkExecCmd(bs store.BookmarkStoreLoader) *cobra.Command {
 				return nil
 			}
 			name := args[0]
-			if _, found := bookmarks[name]; !found {
+			bookmark, found := bookmarks[name]
+			if !found {
 				cmd.Printf("Unable to find bookmark: \"%s\"\n", name)
 				return nil
 			}
-			bookmarkCmdArr := splitOnSpace(bookmarks[name])
-			var command *exec.Cmd
-			cmdAndArgs := bookmarkCmdArr[0]
-			if len(bookmarkCmdArr) > 1 {
-				for _, part := range bookmarkCmdArr[1:] {
-					cmdAndArgs += fmt.Sprintf(" %s", part)
+			values, extra := args[1:], []string(nil)
+			if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+				// A picked bookmark comes before the "--" given without
+				// arguments.
+				if dash < 1 {
+					dash = 1
 				}
+				values, extra = args[1:dash], args[dash:]
 			}
-			if runtime.GOOS == "windows" {
-				command = exec.Command("cmd", "/c", cmdAndArgs)
-			} else {
-				command = exec.Command("bash", "-c", cmdAndArgs)
-			}
-			command.Stdout = os.Stdout
-			command.Stderr = os.Stderr
-			return command.Run()
+			return execBookmark(cmd, bs, p, name, bookmark, values, extra, opts)
 		},
 	}
+	execCmd.Flags().StringArrayVar(&opts.sets, "set", nil, "set a placeholder value (key=value)")
+	execCmd.Flags().StringVar(&opts.shell, "shell", "", "run the command with the given shell instead of the bookmark's shell")
+	execCmd.Flags().DurationVar(&opts.timeout, "timeout", 0, "kill the command if it runs longer than the given duration, e.g. 30s")
+	_ = execCmd.RegisterFlagCompletionFunc("set", completePlaceholders(bs))
+	_ = execCmd.RegisterFlagCompletionFunc("shell", completeShells)
+	return execCmd
+}
+
+// execOptions are the flags of the exec command.
+type execOptions struct {
+	sets    []string
+	shell   string
+	timeout time.Duration
+}
+
+// execBookmark executes bookmark. values fill in the placeholders of its
+// command, the user is asked for missing values with p, and extra are the
+// arguments passed on to the command. URL and file bookmarks are opened
+// and scripts are run with values and extra as their arguments.
+func execBookmark(cmd *cobra.Command, bs store.BookmarkStore, p Prompter, name string, bookmark store.Bookmark, values, extra []string, opts execOptions) error {
+	switch bookmark.Kind {
+	case store.KindURL, store.KindFile:
+		if len(extra) > 0 {
+			return fmt.Errorf("arguments after \"--\" cannot be passed on to a %s bookmark", bookmark.Kind)
+		}
+		return openBookmark(cmd, bs, p, name, bookmark, values, openOptions{sets: opts.sets})
+	case store.KindScript:
+		return execScript(cmd, bs, name, bookmark, append(values, extra...), opts)
+	case store.KindDir:
+		cmd.SilenceUsage = true
+		return kindError(name, bookmark.Kind)
+	}
+	shellName := resolveShell(opts.shell, bookmark.Shell)
+	if err := validateShellName(shellName); err != nil {
+		return err
+	}
+	sh := shells[shellName]
+	resolved, err := resolvePlaceholders(bookmark.Command, opts.sets, values, sh.quote, promptPlaceholder(cmd, p))
+	if err != nil {
+		return err
+	}
+	command, line, err := sh.command(name, resolved, extra)
+	if err != nil {
+		cmd.SilenceUsage = true
+		recordFailure(cmd, bs, name, sh.line(resolved, extra), err)
+		return err
+	}
+	return runBookmark(cmd, bs, name, bookmark, command, line, opts.timeout)
+}
+
+// runBookmark runs command for the bookmark with the given name in the
+// working directory and with the environment of bookmark, and records the
+// use of the bookmark. The run is added to the history with resolved as
+// its command.
+func runBookmark(cmd *cobra.Command, bs store.BookmarkStore, name string, bookmark store.Bookmark, command *exec.Cmd, resolved string, timeout time.Duration) error {
+	if err := configureCommand(command, name, bookmark); err != nil {
+		cmd.SilenceUsage = true
+		recordFailure(cmd, bs, name, resolved, err)
+		return err
+	}
+	if err := recordUse(bs, name); err != nil {
+		return err
+	}
+	command.Stdin = cmd.InOrStdin()
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+	start := time.Now()
+	err := runCommand(command, timeout)
+	recordRun(cmd, bs, history.Entry{
+		Time:       start,
+		Name:       name,
+		Command:    resolved,
+		Cwd:        command.Dir,
+		DurationMs: time.Since(start).Milliseconds(),
+	}, err)
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		cmd.SilenceUsage = true
+		var cmdErr *exec.ExitError
+		if errors.As(err, &cmdErr) {
+			// The command has already reported its own errors.
+			cmd.SilenceErrors = true
+		} else {
+			exitErr.Err = fmt.Errorf("bookmark \"%s\" %w", name, exitErr.Err)
+		}
+	}
+	return err
+}
+
+// recordUse updates the usage statistics of the bookmark with the given
+// name.
+func recordUse(bs store.BookmarkStoreModifier, name string) error {
+	return bs.Modify(func(bookmarks store.BookmarkContainer) error {
+		if bookmark, found := bookmarks[name]; found {
+			now := time.Now()
+			bookmark.LastUsedAt = &now
+			bookmark.RunCount += 1
+			bookmarks[name] = bookmark
+		}
+		return nil
+	})
 }
 
 // BookmarkListCmd initializes a new list command.
-func BookmarkListCmd(bs store.BookmarkStoreLoader) *cobra.Command {
-	return &cobra.Command{
+func BookmarkListCmd(bs store.BookmarkStoreLoadScripter) *cobra.Command {
+	var tags string
+	var out output
+	listCmd := &cobra.Command{
 		Use:   "list",
 		Short: "List your current saved bookmarks",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := out.validate(); err != nil {
+				return err
+			}
 			bookmarks, err := bs.Load()
 			if err != nil {
 				return err
 			}
+			bookmarks, err = filterBookmarks(bookmarks, tags)
+			if err != nil {
+				return err
+			}
 			keys := make([]string, 0, len(bookmarks))
 			for k := range bookmarks {
 				keys = append(keys, k)
 			}
 			sort.Strings(keys)
-			cmd.Println("ID: BOOKMARK: COMMAND")
+			if out.format != "" {
+				records := make([]bookmarkRecord, len(keys))
+				for i, k := range keys {
+					records[i] = newBookmarkRecord(k, bookmarks[k])
+				}
+				return out.writeBookmarks(cmd, records)
+			}
+			cmd.Println("ID: BOOKMARK: KIND: COMMAND")
 			counter := 1
 			for _, k := range keys {
-				cmd.Printf("%d: %s: %s\n", counter, k, bookmarks[k])
+				summary := bookmarks[k].Summary()
+				if bookmarks[k].Kind == store.KindScript {
+					summary = scriptSummary(bs, k, bookmarks[k])
+				}
+				cmd.Printf("%d: %s: %s: %s\n", counter, k, bookmarks[k].Kind, summary)
 				counter += 1
 			}
 			return nil
 		},
 	}
+	listCmd.Flags().StringVar(&tags, "tags", "", "only list bookmarks matching a tag expression, e.g. 'k8s && !prod'")
+	addOutputFlags(listCmd, &out)
+	_ = listCmd.RegisterFlagCompletionFunc("tags", completeTagExpression(bs))
+	return listCmd
 }
 
 // BookmarkRemoveCmd initializes a new remove command.
-func BookmarkRemoveCmd(bs store.BookmarkStoreLoadUpdater) *cobra.Command {
+func BookmarkRemoveCmd(bs store.BookmarkStore, p Prompter) *cobra.Command {
 	return &cobra.Command{
-		Use:   "remove",
-		Short: "Remove a bookmark",
-		Args:  cobra.ExactArgs(1),
+		Use:               "remove <bookmark>",
+		Short:             "Remove a bookmark",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeBookmarks(bs),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			bookmarks, err := bs.Load()
 			if err != nil {
@@ -165,15 +541,24 @@ func BookmarkRemoveCmd(bs store.BookmarkStoreLoadUpdater) *cobra.Command {
 				cmd.Printf("Unable to find bookmark: \"%s\"\n", name)
 				return nil
 			}
-			var input string
-			cmd.Printf("Are you sure you want to remove \"%s\" (y/N)? ", name)
-			_, _ = fmt.Scanln(&input)
-			if strings.ToLower(strings.TrimSpace(input)) == "y" {
-				delete(bookmarks, name)
-				err := bs.Update(bookmarks)
+			remove, err := p.Confirm(cmd, fmt.Sprintf("Are you sure you want to remove \"%s\"", name))
+			if err != nil {
+				return err
+			}
+			if remove {
+				var unused []string
+				err := bs.Modify(func(bookmarks store.BookmarkContainer) error {
+					removed := bookmarks[name]
+					delete(bookmarks, name)
+					unused = unusedScripts(bookmarks, removed)
+					return nil
+				})
 				if err != nil {
 					return err
 				}
+				if err := removeScripts(bs, unused); err != nil {
+					return err
+				}
 				cmd.Printf("\"%s\" was removed successfully!\n", name)
 			}
 			return nil
@@ -183,55 +568,124 @@ func BookmarkRemoveCmd(bs store.BookmarkStoreLoadUpdater) *cobra.Command {
 
 // BookmarkSearchCmd initializes a new search command.
 func BookmarkSearchCmd(bs store.BookmarkStoreLoader) *cobra.Command {
-	return &cobra.Command{
-		Use:   "search",
+	var tags, color string
+	var substring, regex, first bool
+	var limit int
+	var out output
+	searchCmd := &cobra.Command{
+		Use:   "search [query]",
 		Short: "seach for a bookmark",
-		Args:  cobra.ExactArgs(1),
+		Long: `Search the names, commands, descriptions and tags of your bookmarks.
+
+The query is matched fuzzily by default, so "kgp" finds "kubectl get pods".
+Use --substring or --regex to match it as a substring or a regular
+expression instead. Matching ignores case unless the query contains an
+upper case letter, except for regular expressions.
+
+The results are ranked by how well they match, best first. If --tags is
+given without a query, every bookmark matching the tag expression is
+printed.`,
+		ValidArgsFunction: completeBookmarks(bs),
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && !cmd.Flags().Changed("tags") {
+				return cobra.ExactArgs(1)(cmd, args)
+			}
+			return cobra.MaximumNArgs(1)(cmd, args)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := out.validate(); err != nil {
+				return err
+			}
+			colored, err := useColor(cmd, color)
+			if err != nil {
+				return err
+			}
+			var matcher search.Matcher
+			if len(args) > 0 {
+				mode := search.Fuzzy
+				if substring {
+					mode = search.Substring
+				} else if regex {
+					mode = search.Regex
+				}
+				matcher, err = search.NewMatcher(args[0], mode)
+				if err != nil {
+					return err
+				}
+			}
 			bookmarks, err := bs.Load()
 			if err != nil {
 				return err
 			}
-			if len(bookmarks) == 0 {
+			if len(bookmarks) == 0 && out.format == "" {
 				cmd.Println("You have no saved bookmarks")
 				return nil
 			}
-			if val, found := bookmarks[args[0]]; found {
-				cmd.Println(val)
+			bookmarks, err = filterBookmarks(bookmarks, tags)
+			if err != nil {
+				return err
+			}
+			results := search.Search(bookmarks, matcher)
+			if len(results) == 0 && out.format == "" {
+				if first {
+					cmd.SilenceUsage = true
+					return fmt.Errorf("no bookmark matches \"%s\"", strings.Join(args, ""))
+				}
+				cmd.Printf("Unable to find bookmark: \"%s\"\n", strings.Join(args, ""))
+				return nil
+			}
+			if first {
+				cmd.Println(results[0].Name)
 				return nil
 			}
-			cmd.Printf("Unable to find bookmark: \"%s\"\n", args[0])
+			if limit > 0 && len(results) > limit {
+				results = results[:limit]
+			}
+			if out.format != "" {
+				records := make([]searchRecord, len(results))
+				for i, r := range results {
+					records[i] = searchRecord{Score: r.Score, bookmarkRecord: newBookmarkRecord(r.Name, r.Bookmark)}
+				}
+				return out.writeSearchResults(cmd, records)
+			}
+			highlight := func(r search.Result, field, value string) string {
+				f, ok := r.Field(field)
+				if !ok || !colored {
+					return value
+				}
+				return search.Highlight(value, f.Spans, highlightStart, highlightEnd)
+			}
+			cmd.Println("SCORE: BOOKMARK: COMMAND")
+			for _, r := range results {
+				cmd.Printf("%d: %s: %s\n", r.Score, highlight(r, "name", r.Name), highlight(r, search.SummaryField(r.Bookmark), r.Bookmark.Summary()))
+				for _, field := range []string{"description", "tags"} {
+					if f, ok := r.Field(field); ok {
+						cmd.Printf("    %s: %s\n", field, highlight(r, field, f.Value))
+					}
+				}
+			}
 			return nil
 		},
 	}
+	searchCmd.Flags().StringVar(&tags, "tags", "", "only search bookmarks matching a tag expression, e.g. 'k8s && !prod'")
+	searchCmd.Flags().BoolVar(&substring, "substring", false, "match the query as a substring")
+	searchCmd.Flags().BoolVar(&regex, "regex", false, "match the query as a regular expression")
+	searchCmd.Flags().IntVar(&limit, "limit", 0, "show at most this many results, 0 for no limit")
+	searchCmd.Flags().BoolVar(&first, "first", false, "only print the name of the best match")
+	searchCmd.Flags().StringVar(&color, "color", "auto", "highlight matches: always, never or auto")
+	addOutputFlags(searchCmd, &out)
+	searchCmd.MarkFlagsMutuallyExclusive("substring", "regex")
+	searchCmd.MarkFlagsMutuallyExclusive("first", "output")
+	_ = searchCmd.RegisterFlagCompletionFunc("tags", completeTagExpression(bs))
+	_ = searchCmd.RegisterFlagCompletionFunc("color", cobra.FixedCompletions([]string{"auto", "always", "never"}, cobra.ShellCompDirectiveNoFileComp))
+	return searchCmd
 }
 
 func init() {
 	rootCmd.AddCommand(bookmarkAddCmd)
+	rootCmd.AddCommand(bookmarkEditCmd)
 	rootCmd.AddCommand(bookmarkExecCmd)
 	rootCmd.AddCommand(bookmarkListCmd)
 	rootCmd.AddCommand(bookmarkRemoveCmd)
 	rootCmd.AddCommand(bookmarkSearchCmd)
 }
-
-func splitOnSpace(s string) []string {
-	res := []string{}
-	var beg int
-	var inString bool
-	var quote byte
-
-	for i := 0; i < len(s); i++ {
-		if s[i] == ' ' && !inString {
-			res = append(res, s[beg:i])
-			beg = i + 1
-		} else if s[i] == '"' || s[i] == '\'' {
-			if !inString {
-				quote = s[i]
-				inString = true
-			} else if (i > 0 && s[i-1] != '\\') && s[i] == quote {
-				inString = false
-			}
-		}
-	}
-	return append(res, s[beg:])
-}
@@ -2,12 +2,15 @@ package cmd_test
 
 import (
 	"bytes"
+	"errors"
 	"io"
-	"io/ioutil"
-	"log"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/henrikac/bookmark/cmd"
 	"github.com/henrikac/bookmark/internal/store"
@@ -15,7 +18,23 @@ import (
 )
 
 type memoryBookmarkStore struct {
-	Bookmarks map[string]string
+	Bookmarks store.BookmarkContainer
+	// Scripts is the script directory of the store.
+	Scripts string
+	// History is the path to the history of the store. Nothing is recorded
+	// if it is empty.
+	History string
+	// ModifyErr is returned by Modify after fn changed a copy of the
+	// bookmarks, as if the store could not be written.
+	ModifyErr error
+}
+
+func (s *memoryBookmarkStore) ScriptDir() string {
+	return s.Scripts
+}
+
+func (s *memoryBookmarkStore) HistoryPath() string {
+	return s.History
 }
 
 func (s *memoryBookmarkStore) Load() (store.BookmarkContainer, error) {
@@ -26,6 +45,20 @@ func (s *memoryBookmarkStore) Update(store store.BookmarkContainer) error {
 	return nil
 }
 
+func (s *memoryBookmarkStore) Modify(fn func(store.BookmarkContainer) error) error {
+	if s.ModifyErr == nil {
+		return fn(s.Bookmarks)
+	}
+	bookmarks := make(store.BookmarkContainer, len(s.Bookmarks))
+	for name, bookmark := range s.Bookmarks {
+		bookmarks[name] = bookmark
+	}
+	if err := fn(bookmarks); err != nil {
+		return err
+	}
+	return s.ModifyErr
+}
+
 func newMemoryBookmarkStore() *memoryBookmarkStore {
 	return &memoryBookmarkStore{
 		Bookmarks: store.BookmarkContainer{},
@@ -41,21 +74,6 @@ func executeCommand(cmd *cobra.Command, args ...string) (string, error) {
 	return buff.String(), err
 }
 
-func userInput(input string) *os.File {
-	content := []byte(input)
-	tmpfile, err := ioutil.TempFile("", "example")
-	if err != nil {
-		log.Fatal(err)
-	}
-	if _, err := tmpfile.Write(content); err != nil {
-		log.Fatal(err)
-	}
-	if _, err := tmpfile.Seek(0, 0); err != nil {
-		log.Fatal(err)
-	}
-	return tmpfile
-}
-
 // capture os.Stdout
 func capture() func() (string, error) {
 	r, w, err := os.Pipe()
@@ -87,7 +105,7 @@ func capture() func() (string, error) {
 func TestBookmarkAddCmd(t *testing.T) {
 	s := newMemoryBookmarkStore()
 	root := cmd.NewRootCmd()
-	addCmd := cmd.BookmarkAddCmd(s)
+	addCmd := cmd.BookmarkAddCmd(s, cmd.NewPrompter())
 	bookmarkName := "hello"
 	bookmarkCmd := "echo \"Hello World\""
 	root.AddCommand(addCmd)
@@ -108,8 +126,39 @@ func TestBookmarkAddCmd(t *testing.T) {
 	if !found {
 		t.Errorf("Expected to find bookmark named \"%s\"", bookmarkName)
 	}
-	if val != bookmarkCmd {
-		t.Errorf("Expected to find: %s\nFound: %s", bookmarkCmd, val)
+	if val.Command != bookmarkCmd {
+		t.Errorf("Expected to find: %s\nFound: %s", bookmarkCmd, val.Command)
+	}
+	if val.CreatedAt.IsZero() {
+		t.Error("Expected the bookmark to have a creation time")
+	}
+}
+
+func TestBookmarkAddCmdInvalidCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("commands are not validated on windows")
+	}
+	s := newMemoryBookmarkStore()
+	root := cmd.NewRootCmd()
+	addCmd := cmd.BookmarkAddCmd(s, cmd.NewPrompter())
+	root.AddCommand(addCmd)
+	output, err := executeCommand(root, "add", "broken", "ls | | wc")
+	if err == nil {
+		t.Error("Expected an error for an invalid command")
+	}
+	if !strings.HasPrefix(output, "ls | | wc\n     ^ unexpected \"|\"\n") {
+		t.Errorf("Expected the syntax error to be highlighted\nGot: %s", output)
+	}
+	if len(s.Bookmarks) != 0 {
+		t.Errorf("Expected no stored bookmarks\nFound: %d", len(s.Bookmarks))
+	}
+
+	_, err = executeCommand(root, "add", "broken", "--no-validate", "ls | | wc")
+	if err != nil {
+		t.Errorf("Error: %s", err)
+	}
+	if len(s.Bookmarks) != 1 {
+		t.Errorf("Expected 1 stored bookmark\nFound: %d", len(s.Bookmarks))
 	}
 }
 
@@ -122,7 +171,7 @@ func TestBookmarkListCmdWithNoBookmarks(t *testing.T) {
 	if err != nil {
 		t.Errorf("Error: %s", err)
 	}
-	expected := "ID: BOOKMARK: COMMAND\n"
+	expected := "ID: BOOKMARK: KIND: COMMAND\n"
 	if output != expected {
 		t.Errorf("Expected: %s\nGot: %s", expected, output)
 	}
@@ -130,8 +179,8 @@ func TestBookmarkListCmdWithNoBookmarks(t *testing.T) {
 
 func TestBookmarkListCmd(t *testing.T) {
 	s := newMemoryBookmarkStore()
-	s.Bookmarks["hello"] = "echo \"Hello world\""
-	s.Bookmarks["list"] = "ls"
+	s.Bookmarks["hello"] = store.Bookmark{Command: "echo \"Hello world\""}
+	s.Bookmarks["list"] = store.Bookmark{Command: "ls"}
 	root := cmd.NewRootCmd()
 	listCmd := cmd.BookmarkListCmd(s)
 	root.AddCommand(listCmd)
@@ -142,9 +191,9 @@ func TestBookmarkListCmd(t *testing.T) {
 	if output == "" {
 		t.Error("Expected output but got none")
 	}
-	expectedOutput := `ID: BOOKMARK: COMMAND
-1: hello: echo "Hello world"
-2: list: ls
+	expectedOutput := `ID: BOOKMARK: KIND: COMMAND
+1: hello: command: echo "Hello world"
+2: list: command: ls
 `
 	if output != expectedOutput {
 		t.Errorf("Expected:\n%s\nGot:\n%s", expectedOutput, output)
@@ -154,7 +203,7 @@ func TestBookmarkListCmd(t *testing.T) {
 func TestBookmarkRemoveCmdWithNoBookmarks(t *testing.T) {
 	s := newMemoryBookmarkStore()
 	root := cmd.NewRootCmd()
-	removeCmd := cmd.BookmarkRemoveCmd(s)
+	removeCmd := cmd.BookmarkRemoveCmd(s, cmd.NewPrompter())
 	root.AddCommand(removeCmd)
 	output, err := executeCommand(root, "remove", "test")
 	if err != nil {
@@ -168,9 +217,9 @@ func TestBookmarkRemoveCmdWithNoBookmarks(t *testing.T) {
 
 func TestBookmarkRemoveCmdUnknownCmd(t *testing.T) {
 	s := newMemoryBookmarkStore()
-	s.Bookmarks["test"] = "bad command"
+	s.Bookmarks["test"] = store.Bookmark{Command: "bad command"}
 	root := cmd.NewRootCmd()
-	removeCmd := cmd.BookmarkRemoveCmd(s)
+	removeCmd := cmd.BookmarkRemoveCmd(s, cmd.NewPrompter())
 	root.AddCommand(removeCmd)
 	output, err := executeCommand(root, "remove", "unknown")
 	if err != nil {
@@ -183,17 +232,12 @@ func TestBookmarkRemoveCmdUnknownCmd(t *testing.T) {
 }
 
 func TestBookmarkRemoveCmd(t *testing.T) {
-	input := userInput("y")
-	defer os.Remove(input.Name())
-	oldStdin := os.Stdin
-	defer func() { os.Stdin = oldStdin }()
-	os.Stdin = input
-
 	s := newMemoryBookmarkStore()
-	s.Bookmarks["test"] = "bad command"
+	s.Bookmarks["test"] = store.Bookmark{Command: "bad command"}
 	root := cmd.NewRootCmd()
-	removeCmd := cmd.BookmarkRemoveCmd(s)
+	removeCmd := cmd.BookmarkRemoveCmd(s, cmd.NewPrompter())
 	root.AddCommand(removeCmd)
+	root.SetIn(strings.NewReader("y\n"))
 	output, err := executeCommand(root, "remove", "test")
 	if err != nil {
 		t.Errorf("Error: %s", err)
@@ -214,7 +258,7 @@ func TestBookmarkRemoveCmd(t *testing.T) {
 func TestBookmarkExecCmdWithNoBookmarks(t *testing.T) {
 	s := newMemoryBookmarkStore()
 	root := cmd.NewRootCmd()
-	execCmd := cmd.BookmarkExecCmd(s)
+	execCmd := cmd.BookmarkExecCmd(s, cmd.NewPrompter())
 	root.AddCommand(execCmd)
 	output, err := executeCommand(root, "exec", "test")
 	if err != nil {
@@ -228,9 +272,9 @@ func TestBookmarkExecCmdWithNoBookmarks(t *testing.T) {
 
 func TestBookmarkExecCmdWithUnknownCommand(t *testing.T) {
 	s := newMemoryBookmarkStore()
-	s.Bookmarks["hello"] = "echo \"Hello world\""
+	s.Bookmarks["hello"] = store.Bookmark{Command: "echo \"Hello world\""}
 	root := cmd.NewRootCmd()
-	execCmd := cmd.BookmarkExecCmd(s)
+	execCmd := cmd.BookmarkExecCmd(s, cmd.NewPrompter())
 	root.AddCommand(execCmd)
 	output, err := executeCommand(root, "exec", "test")
 	if err != nil {
@@ -244,9 +288,9 @@ func TestBookmarkExecCmdWithUnknownCommand(t *testing.T) {
 
 func TestBookmarkExecCmd(t *testing.T) {
 	s := newMemoryBookmarkStore()
-	s.Bookmarks["hello"] = "echo \"Hello world\""
+	s.Bookmarks["hello"] = store.Bookmark{Command: "echo \"Hello world\""}
 	root := cmd.NewRootCmd()
-	execCmd := cmd.BookmarkExecCmd(s)
+	execCmd := cmd.BookmarkExecCmd(s, cmd.NewPrompter())
 	root.AddCommand(execCmd)
 	done := capture()
 	_, err := executeCommand(root, "exec", "hello")
@@ -261,6 +305,13 @@ func TestBookmarkExecCmd(t *testing.T) {
 	if output != expected {
 		t.Errorf("Expected: %s\nGot: %s", expected, output)
 	}
+	bookmark := s.Bookmarks["hello"]
+	if bookmark.RunCount != 1 {
+		t.Errorf("Expected run count: 1\nGot: %d", bookmark.RunCount)
+	}
+	if bookmark.LastUsedAt == nil {
+		t.Error("Expected the last used time to be set")
+	}
 }
 
 func TestBookmarkSearchCmdWithNoBookmarks(t *testing.T) {
@@ -280,7 +331,7 @@ func TestBookmarkSearchCmdWithNoBookmarks(t *testing.T) {
 
 func TestBookmarkSearchCmdUnknownBookmark(t *testing.T) {
 	s := newMemoryBookmarkStore()
-	s.Bookmarks["hello"] = "echo \"Hello world\""
+	s.Bookmarks["hello"] = store.Bookmark{Command: "echo \"Hello world\""}
 	root := cmd.NewRootCmd()
 	execCmd := cmd.BookmarkSearchCmd(s)
 	root.AddCommand(execCmd)
@@ -295,17 +346,414 @@ func TestBookmarkSearchCmdUnknownBookmark(t *testing.T) {
 }
 
 func TestBookmarkSearchCmd(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			"fuzzy",
+			[]string{"search", "hlo"},
+			"SCORE: BOOKMARK: COMMAND\n320: hello: echo \"Hello world\"\n128: greet: echo \"Hello world\"\n    description: say hello to the world\n",
+		},
+		{
+			"highlight",
+			[]string{"search", "--color", "always", "--substring", "wor"},
+			"SCORE: BOOKMARK: COMMAND\n" +
+				"112: greet: echo \"Hello \x1b[1;31mwor\x1b[0mld\"\n" +
+				"    description: say hello to the \x1b[1;31mwor\x1b[0mld\n" +
+				"57: hello: echo \"Hello \x1b[1;31mwor\x1b[0mld\"\n",
+		},
+		{
+			"regex",
+			[]string{"search", "--regex", "^l"},
+			"SCORE: BOOKMARK: COMMAND\n128: list: ls\n",
+		},
+		{
+			"tags",
+			[]string{"search", "--substring", "greeting"},
+			"SCORE: BOOKMARK: COMMAND\n558: greet: echo \"Hello world\"\n    tags: greeting\n",
+		},
+		{"limit", []string{"search", "--limit", "1", "hlo"}, "SCORE: BOOKMARK: COMMAND\n320: hello: echo \"Hello world\"\n"},
+		{"first", []string{"search", "--first", "hlo"}, "hello\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newMemoryBookmarkStore()
+			s.Bookmarks["hello"] = store.Bookmark{Command: "echo \"Hello world\""}
+			s.Bookmarks["greet"] = store.Bookmark{
+				Command:     "echo \"Hello world\"",
+				Description: "say hello to the world",
+				Tags:        []string{"greeting"},
+			}
+			s.Bookmarks["list"] = store.Bookmark{Command: "ls"}
+			root := cmd.NewRootCmd()
+			searchCmd := cmd.BookmarkSearchCmd(s)
+			root.AddCommand(searchCmd)
+			output, err := executeCommand(root, tt.args...)
+			if err != nil {
+				t.Errorf("Error: %s", err)
+			}
+			if output != tt.expected {
+				t.Errorf("Expected: %q\nGot: %q", tt.expected, output)
+			}
+		})
+	}
+}
+
+func TestBookmarkSearchCmdFirstWithoutMatch(t *testing.T) {
 	s := newMemoryBookmarkStore()
-	s.Bookmarks["hello"] = "echo \"Hello world\""
+	s.Bookmarks["hello"] = store.Bookmark{Command: "echo \"Hello world\""}
 	root := cmd.NewRootCmd()
-	execCmd := cmd.BookmarkSearchCmd(s)
+	searchCmd := cmd.BookmarkSearchCmd(s)
+	root.AddCommand(searchCmd)
+	_, err := executeCommand(root, "search", "--first", "xyz")
+	if err == nil {
+		t.Error("Expected an error when nothing matches")
+	}
+}
+
+func TestBookmarkExecCmdWithPlaceholders(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		input    string
+		expected string
+	}{
+		{"set", []string{"--set", "name=World"}, "", "Hello World!\n"},
+		{"positional", []string{"World", "?"}, "", "Hello World?\n"},
+		{"prompt", nil, "World\n", "Hello World!\n"},
+		{"quoting", []string{"--set", "name=$(echo injected)"}, "", "Hello $(echo injected)!\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newMemoryBookmarkStore()
+			s.Bookmarks["greet"] = store.Bookmark{Command: "echo \"Hello {{name}}\"{{punct:!}}"}
+			root := cmd.NewRootCmd()
+			execCmd := cmd.BookmarkExecCmd(s, cmd.NewPrompter())
+			root.AddCommand(execCmd)
+			root.SetIn(strings.NewReader(tt.input))
+			done := capture()
+			_, err := executeCommand(root, append([]string{"exec", "greet"}, tt.args...)...)
+			if err != nil {
+				t.Errorf("Error: %s", err)
+			}
+			output, err := done()
+			if err != nil {
+				t.Errorf("Error: %s", err)
+			}
+			if output != tt.expected {
+				t.Errorf("Expected: %s\nGot: %s", tt.expected, output)
+			}
+		})
+	}
+}
+
+func TestBookmarkExecCmdWithUnknownPlaceholder(t *testing.T) {
+	s := newMemoryBookmarkStore()
+	s.Bookmarks["greet"] = store.Bookmark{Command: "echo {{name}}"}
+	root := cmd.NewRootCmd()
+	execCmd := cmd.BookmarkExecCmd(s, cmd.NewPrompter())
+	root.AddCommand(execCmd)
+	_, err := executeCommand(root, "exec", "greet", "--set", "nmae=World")
+	if err == nil {
+		t.Error("Expected an error for an unknown placeholder")
+	}
+}
+
+func TestBookmarkExecCmdWithExtraArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		args     []string
+		expected string
+	}{
+		{"appended", "echo hello", []string{"--", "--name", "big world"}, "hello --name big world\n"},
+		{"positional", "echo \"$2 $1\"", []string{"--", "a b", "c"}, "c a b\n"},
+		{"all", "printf '%s|' \"$@\"", []string{"--", "a b", "c"}, "a b|c|"},
+		{"with placeholder", "echo {{greeting}}", []string{"hi", "--", "there"}, "hi there\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newMemoryBookmarkStore()
+			s.Bookmarks["test"] = store.Bookmark{Command: tt.command}
+			root := cmd.NewRootCmd()
+			execCmd := cmd.BookmarkExecCmd(s, cmd.NewPrompter())
+			root.AddCommand(execCmd)
+			done := capture()
+			_, err := executeCommand(root, append([]string{"exec", "test"}, tt.args...)...)
+			if err != nil {
+				t.Errorf("Error: %s", err)
+			}
+			output, err := done()
+			if err != nil {
+				t.Errorf("Error: %s", err)
+			}
+			if output != tt.expected {
+				t.Errorf("Expected: %s\nGot: %s", tt.expected, output)
+			}
+		})
+	}
+}
+
+func TestBookmarkExecCmdDashBeforeName(t *testing.T) {
+	s := newMemoryBookmarkStore()
+	s.Bookmarks["foo"] = store.Bookmark{Command: "echo foo"}
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.BookmarkExecCmd(s, cmd.NewPrompter()))
+	_, err := executeCommand(root, "exec", "--", "foo", "bar")
+	expected := "the bookmark must be named before \"--\", e.g. bookmark exec <bookmark> -- foo bar"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected: %s\nGot: %v", expected, err)
+	}
+	if s.Bookmarks["foo"].RunCount != 0 {
+		t.Errorf("Expected foo not to run\nGot: %+v", s.Bookmarks["foo"])
+	}
+}
+
+func TestBookmarkExecCmdStdin(t *testing.T) {
+	s := newMemoryBookmarkStore()
+	s.Bookmarks["upper"] = store.Bookmark{Command: "tr a-z A-Z"}
+	root := cmd.NewRootCmd()
+	execCmd := cmd.BookmarkExecCmd(s, cmd.NewPrompter())
+	root.AddCommand(execCmd)
+	root.SetIn(strings.NewReader("hello from stdin\n"))
+	done := capture()
+	_, err := executeCommand(root, "exec", "upper")
+	if err != nil {
+		t.Errorf("Error: %s", err)
+	}
+	output, err := done()
+	if err != nil {
+		t.Errorf("Error: %s", err)
+	}
+	expected := "HELLO FROM STDIN\n"
+	if output != expected {
+		t.Errorf("Expected: %s\nGot: %s", expected, output)
+	}
+}
+
+func TestBookmarkExecCmdExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		expected int
+	}{
+		{"exit code", "exit 3", 3},
+		{"command not found", "this-command-does-not-exist-42", 127},
+		{"signal", "kill -TERM $$", 128 + 15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newMemoryBookmarkStore()
+			s.Bookmarks["fail"] = store.Bookmark{Command: tt.command}
+			root := cmd.NewRootCmd()
+			execCmd := cmd.BookmarkExecCmd(s, cmd.NewPrompter())
+			root.AddCommand(execCmd)
+			output, err := executeCommand(root, "exec", "fail")
+			var exitErr *cmd.ExitError
+			if !errors.As(err, &exitErr) {
+				t.Fatalf("Expected an ExitError\nGot: %v", err)
+			}
+			if exitErr.Code != tt.expected {
+				t.Errorf("Expected exit code: %d\nGot: %d", tt.expected, exitErr.Code)
+			}
+			if output != "" {
+				t.Errorf("Expected no output\nGot: %s", output)
+			}
+		})
+	}
+}
+
+func TestBookmarkExecCmdTimeout(t *testing.T) {
+	s := newMemoryBookmarkStore()
+	s.Bookmarks["slow"] = store.Bookmark{Command: "sleep 10 & sleep 10"}
+	root := cmd.NewRootCmd()
+	execCmd := cmd.BookmarkExecCmd(s, cmd.NewPrompter())
 	root.AddCommand(execCmd)
-	output, err := executeCommand(root, "search", "hello")
+	start := time.Now()
+	output, err := executeCommand(root, "exec", "slow", "--timeout", "100ms")
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the command to be killed after the timeout\nTook: %s", elapsed)
+	}
+	var exitErr *cmd.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("Expected an ExitError\nGot: %v", err)
+	}
+	if exitErr.Code != 124 {
+		t.Errorf("Expected exit code: 124\nGot: %d", exitErr.Code)
+	}
+	expected := "Error: bookmark \"slow\" timed out after 100ms\n"
+	if output != expected {
+		t.Errorf("Expected: %s\nGot: %s", expected, output)
+	}
+}
+
+func TestBookmarkExecCmdForwardsSignals(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals are not forwarded on windows")
+	}
+	s := newMemoryBookmarkStore()
+	// The command signals bookmark itself, which must forward the signal
+	// back to the command.
+	s.Bookmarks["trap"] = store.Bookmark{Command: "trap 'exit 7' TERM; kill -TERM $PPID; sleep 10 & wait"}
+	root := cmd.NewRootCmd()
+	execCmd := cmd.BookmarkExecCmd(s, cmd.NewPrompter())
+	root.AddCommand(execCmd)
+	_, err := executeCommand(root, "exec", "trap", "--timeout", "5s")
+	var exitErr *cmd.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("Expected an ExitError\nGot: %v", err)
+	}
+	if exitErr.Code != 7 {
+		t.Errorf("Expected exit code: 7\nGot: %d", exitErr.Code)
+	}
+}
+
+func TestBookmarkExecCmdWithShell(t *testing.T) {
+	tests := []struct {
+		name     string
+		shell    string
+		command  string
+		args     []string
+		expected string
+	}{
+		{"sh", "sh", "echo {{msg}} | tr a-z A-Z", []string{"hi there"}, "HI THERE\n"},
+		{"none", "none", "printf '%s|' {{msg}}", []string{"a b", "--", "c d"}, "a b|c d|"},
+		{"python", "python", "import sys; print({{msg}}, sys.argv[1:])", []string{"it's", "--", "x"}, "it's ['x']\n"},
+		{"flag overrides bookmark", "python", "echo $0", []string{"--shell", "sh"}, "test\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := exec.LookPath(tt.shell); err != nil && tt.shell != "none" {
+				if _, err := exec.LookPath(tt.shell + "3"); err != nil {
+					t.Skipf("%s is not installed", tt.shell)
+				}
+			}
+			s := newMemoryBookmarkStore()
+			s.Bookmarks["test"] = store.Bookmark{Command: tt.command, Shell: tt.shell}
+			root := cmd.NewRootCmd()
+			execCmd := cmd.BookmarkExecCmd(s, cmd.NewPrompter())
+			root.AddCommand(execCmd)
+			done := capture()
+			_, err := executeCommand(root, append([]string{"exec", "test"}, tt.args...)...)
+			if err != nil {
+				t.Errorf("Error: %s", err)
+			}
+			output, err := done()
+			if err != nil {
+				t.Errorf("Error: %s", err)
+			}
+			if output != tt.expected {
+				t.Errorf("Expected: %s\nGot: %s", tt.expected, output)
+			}
+		})
+	}
+}
+
+func TestBookmarkExecCmdShellNotFound(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	s := newMemoryBookmarkStore()
+	s.Bookmarks["test"] = store.Bookmark{Command: "echo $HOME", Shell: "zsh"}
+	root := cmd.NewRootCmd()
+	execCmd := cmd.BookmarkExecCmd(s, cmd.NewPrompter())
+	root.AddCommand(execCmd)
+	_, err := executeCommand(root, "exec", "test")
+	expected := "shell \"zsh\" for bookmark \"test\" was not found in PATH"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected: %s\nGot: %v", expected, err)
+	}
+	if s.Bookmarks["test"].RunCount != 0 {
+		t.Error("Expected the run not to be recorded")
+	}
+}
+
+func TestBookmarkAddCmdWithShell(t *testing.T) {
+	s := newMemoryBookmarkStore()
+	root := cmd.NewRootCmd()
+	addCmd := cmd.BookmarkAddCmd(s, cmd.NewPrompter())
+	root.AddCommand(addCmd)
+	_, err := executeCommand(root, "add", "--shell", "python", "hello", "print('hello')")
+	if err != nil {
+		t.Errorf("Error: %s", err)
+	}
+	if s.Bookmarks["hello"].Shell != "python" {
+		t.Errorf("Expected shell: python\nGot: %s", s.Bookmarks["hello"].Shell)
+	}
+	_, err = executeCommand(root, "add", "--shell", "tcsh", "other", "echo hi")
+	if err == nil {
+		t.Error("Expected an error for an unknown shell")
+	}
+}
+
+func TestBookmarkExecCmdWithCwdAndEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+	dir := t.TempDir()
+	t.Setenv("BOOKMARK_TEST_DIR", dir)
+	s := newMemoryBookmarkStore()
+	s.Bookmarks["test"] = store.Bookmark{
+		Command: "pwd && echo $GREETING $NAME",
+		Cwd:     "$BOOKMARK_TEST_DIR",
+		Env:     map[string]string{"GREETING": "hello", "NAME": "world"},
+	}
+	s.Bookmarks["missing"] = store.Bookmark{Command: "pwd", Cwd: filepath.Join(dir, "missing")}
+	root := cmd.NewRootCmd()
+	execCmd := cmd.BookmarkExecCmd(s, cmd.NewPrompter())
+	root.AddCommand(execCmd)
+	done := capture()
+	_, err := executeCommand(root, "exec", "test")
+	if err != nil {
+		t.Errorf("Error: %s", err)
+	}
+	output, err := done()
 	if err != nil {
 		t.Errorf("Error: %s", err)
 	}
-	expected := "echo \"Hello world\"\n"
+	resolved, _ := filepath.EvalSymlinks(dir)
+	expected := resolved + "\nhello world\n"
 	if output != expected {
 		t.Errorf("Expected: %s\nGot: %s", expected, output)
 	}
+	_, err = executeCommand(root, "exec", "missing")
+	if err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("Expected a missing directory error\nGot: %v", err)
+	}
+}
+
+func TestBookmarkAddAndEditCmdWithCwdAndEnv(t *testing.T) {
+	s := newMemoryBookmarkStore()
+	root := cmd.NewRootCmd()
+	addCmd := cmd.BookmarkAddCmd(s, cmd.NewPrompter())
+	editCmd := cmd.BookmarkEditCmd(s)
+	root.AddCommand(addCmd, editCmd)
+	_, err := executeCommand(root, "add", "--cwd", "~/src", "--env", "AWS_PROFILE=dev", "--env", "A=b=c", "deploy", "make deploy")
+	if err != nil {
+		t.Errorf("Error: %s", err)
+	}
+	bookmark := s.Bookmarks["deploy"]
+	if bookmark.Cwd != "~/src" {
+		t.Errorf("Expected cwd: ~/src\nGot: %s", bookmark.Cwd)
+	}
+	if len(bookmark.Env) != 2 || bookmark.Env["AWS_PROFILE"] != "dev" || bookmark.Env["A"] != "b=c" {
+		t.Errorf("Unexpected env: %v", bookmark.Env)
+	}
+
+	_, err = executeCommand(root, "edit", "--cwd", "", "--env", "KUBECONFIG=~/.kube/dev", "--unset-env", "A", "deploy")
+	if err != nil {
+		t.Errorf("Error: %s", err)
+	}
+	bookmark = s.Bookmarks["deploy"]
+	if bookmark.Cwd != "" {
+		t.Errorf("Expected no cwd\nGot: %s", bookmark.Cwd)
+	}
+	if len(bookmark.Env) != 2 || bookmark.Env["AWS_PROFILE"] != "dev" || bookmark.Env["KUBECONFIG"] != "~/.kube/dev" {
+		t.Errorf("Unexpected env: %v", bookmark.Env)
+	}
+
+	_, err = executeCommand(root, "add", "--env", "NOVALUE", "other", "echo")
+	if err == nil {
+		t.Error("Expected an error for an invalid environment variable")
+	}
 }
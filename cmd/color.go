@@ -0,0 +1,57 @@
+// Copyright (C) 2022 Henrik A. Christensen
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// ANSI escape sequences used to highlight output.
+const (
+	highlightStart = "\x1b[1;31m"
+	highlightEnd   = "\x1b[0m"
+	dimStart       = "\x1b[2m"
+)
+
+// useColor reports whether output written by cmd should be colored. mode is
+// the value of a --color flag: always, never or auto. In auto mode output is
+// colored if it is written to a terminal and NO_COLOR is not set.
+func useColor(cmd *cobra.Command, mode string) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		if _, found := os.LookupEnv("NO_COLOR"); found {
+			return false, nil
+		}
+		return isTerminal(cmd.OutOrStdout()), nil
+	default:
+		return false, fmt.Errorf("invalid color mode \"%s\", expected always, never or auto", mode)
+	}
+}
+
+// isTerminal reports whether w is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
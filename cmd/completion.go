@@ -0,0 +1,399 @@
+// Copyright (C) 2022 Henrik A. Christensen
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/henrikac/bookmark/internal/placeholder"
+	"github.com/henrikac/bookmark/internal/store"
+	"github.com/spf13/cobra"
+)
+
+// completionShells are the shells completion scripts can be generated for.
+var completionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+var completionCmd = NewCompletionCmd()
+
+// NewCompletionCmd initializes a new completion command. It replaces the
+// default completion command of cobra in order to add the install
+// subcommand.
+func NewCompletionCmd() *cobra.Command {
+	completionCmd := &cobra.Command{
+		Use:   "completion",
+		Short: "Generate the autocompletion script for the specified shell",
+		Long: `Generate the autocompletion script for the specified shell.
+
+Use "bookmark completion install" to install the script for your shell.`,
+		Args:              cobra.NoArgs,
+		ValidArgsFunction: cobra.NoFileCompletions,
+	}
+	for _, shell := range completionShells {
+		shell := shell
+		completionCmd.AddCommand(&cobra.Command{
+			Use:               shell,
+			Short:             fmt.Sprintf("Generate the autocompletion script for %s", shell),
+			Args:              cobra.NoArgs,
+			ValidArgsFunction: cobra.NoFileCompletions,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return writeCompletion(cmd.Root(), shell, cmd.OutOrStdout())
+			},
+		})
+	}
+	completionCmd.AddCommand(NewCompletionInstallCmd())
+	return completionCmd
+}
+
+// NewCompletionInstallCmd initializes a new completion install command.
+func NewCompletionInstallCmd() *cobra.Command {
+	var shellFlag, pathFlag string
+	installCmd := &cobra.Command{
+		Use:   "install",
+		Short: "Install the autocompletion script for your shell",
+		Long: `Install the autocompletion script for your shell.
+
+The shell is detected from the SHELL environment variable unless --shell is
+given. The script is written to a directory the shell loads completions
+from:
+
+  bash        ~/.local/share/bash-completion/completions/bookmark
+  zsh         ~/.zsh/completions/_bookmark
+  fish        ~/.config/fish/completions/bookmark.fish
+  powershell  <config dir>/bookmark/completion.ps1`,
+		Args:              cobra.NoArgs,
+		ValidArgsFunction: cobra.NoFileCompletions,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			shell, path := shellFlag, pathFlag
+			if shell == "" {
+				shell = detectShell()
+				if shell == "" {
+					return errors.New("unable to detect your shell, use --shell")
+				}
+			}
+			if !contains(completionShells, shell) {
+				return fmt.Errorf("unsupported shell \"%s\", expected one of: %s", shell, strings.Join(completionShells, ", "))
+			}
+			if path == "" {
+				var err error
+				path, err = completionPath(shell, cmd.Root().Name())
+				if err != nil {
+					return err
+				}
+			}
+			var script bytes.Buffer
+			if err := writeCompletion(cmd.Root(), shell, &script); err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(path, script.Bytes(), 0644); err != nil {
+				return err
+			}
+			cmd.Printf("The %s completion script was installed in %s\n", shell, path)
+			switch shell {
+			case "bash":
+				cmd.Println("Completions are loaded by the bash-completion package in new shells.")
+			case "zsh":
+				cmd.Printf("Add the following to your ~/.zshrc if the directory is not in your fpath already:\n\n")
+				cmd.Printf("  fpath=(%s $fpath)\n  autoload -U compinit && compinit\n", filepath.Dir(path))
+			case "fish":
+				cmd.Println("Completions are loaded in new shells.")
+			case "powershell":
+				cmd.Printf("Add the following to your PowerShell profile ($PROFILE):\n\n  . %s\n", path)
+			}
+			return nil
+		},
+	}
+	installCmd.Flags().StringVar(&shellFlag, "shell", "", "the shell to install the script for: "+strings.Join(completionShells, ", "))
+	installCmd.Flags().StringVar(&pathFlag, "path", "", "the file the script is written to")
+	_ = installCmd.RegisterFlagCompletionFunc("shell", cobra.FixedCompletions(completionShells, cobra.ShellCompDirectiveNoFileComp))
+	return installCmd
+}
+
+// writeCompletion writes the completion script of root for shell to w.
+func writeCompletion(root *cobra.Command, shell string, w io.Writer) error {
+	switch shell {
+	case "bash":
+		return root.GenBashCompletionV2(w, true)
+	case "zsh":
+		return root.GenZshCompletion(w)
+	case "fish":
+		return root.GenFishCompletion(w, true)
+	case "powershell":
+		return root.GenPowerShellCompletionWithDesc(w)
+	}
+	return fmt.Errorf("unsupported shell \"%s\"", shell)
+}
+
+// detectShell returns the name of the user's shell, or an empty string if
+// it cannot be detected.
+func detectShell() string {
+	switch name := strings.TrimSuffix(filepath.Base(os.Getenv("SHELL")), ".exe"); name {
+	case "bash", "zsh", "fish":
+		return name
+	case "pwsh", "powershell":
+		return "powershell"
+	}
+	if runtime.GOOS == "windows" {
+		return "powershell"
+	}
+	return ""
+}
+
+// completionPath returns the file the completion script of the program
+// with the given name is installed in for shell.
+func completionPath(shell, name string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	switch shell {
+	case "bash":
+		if dir := os.Getenv("BASH_COMPLETION_USER_DIR"); dir != "" {
+			return filepath.Join(dir, "completions", name), nil
+		}
+		dataHome := os.Getenv("XDG_DATA_HOME")
+		if dataHome == "" {
+			dataHome = filepath.Join(home, ".local", "share")
+		}
+		return filepath.Join(dataHome, "bash-completion", "completions", name), nil
+	case "zsh":
+		return filepath.Join(home, ".zsh", "completions", "_"+name), nil
+	case "fish":
+		configHome := os.Getenv("XDG_CONFIG_HOME")
+		if configHome == "" {
+			configHome = filepath.Join(home, ".config")
+		}
+		return filepath.Join(configHome, "fish", "completions", name+".fish"), nil
+	case "powershell":
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(configDir, name, "completion.ps1"), nil
+	}
+	return "", fmt.Errorf("unsupported shell \"%s\"", shell)
+}
+
+// completeBookmarks completes the name of a bookmark as the first argument
+// of a command. The description or command of every bookmark is shown by
+// shells that support descriptions.
+func completeBookmarks(bs store.BookmarkStoreLoader) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		bookmarks, err := bs.Load()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		return bookmarkCompletions(bookmarks, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// bookmarkCompletions returns the names of the bookmarks that start with
+// prefix together with their description.
+func bookmarkCompletions(bookmarks store.BookmarkContainer, prefix string) []string {
+	var completions []string
+	for name, bookmark := range bookmarks {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		description := bookmark.Description
+		if description == "" {
+			description = bookmark.Summary()
+		}
+		description = strings.Join(strings.Fields(description), " ")
+		completions = append(completions, name+"\t"+description)
+	}
+	sort.Strings(completions)
+	return completions
+}
+
+// ofKind returns the bookmarks of the given kinds.
+func ofKind(bookmarks store.BookmarkContainer, kinds ...store.Kind) store.BookmarkContainer {
+	filtered := make(store.BookmarkContainer)
+	for name, bookmark := range bookmarks {
+		for _, kind := range kinds {
+			if bookmark.Kind == kind {
+				filtered[name] = bookmark
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// completeExecArgs completes the name of a bookmark of one of the given
+// kinds, followed by the default values of its placeholders.
+func completeExecArgs(bs store.BookmarkStoreLoader, kinds ...store.Kind) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		bookmarks, err := bs.Load()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		if len(args) == 0 {
+			return bookmarkCompletions(ofKind(bookmarks, kinds...), toComplete), cobra.ShellCompDirectiveNoFileComp
+		}
+		// Arguments after "--" are passed on to the command. Cobra parses
+		// the arguments being completed with a trailing "--", so only a dash
+		// followed by arguments is seen here.
+		if dash := cmd.ArgsLenAtDash(); dash >= 0 && dash < len(args) {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+		// Scripts take any arguments.
+		if bookmarks[args[0]].Kind == store.KindScript {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+		placeholders := placeholder.Parse(bookmarks[args[0]].Summary())
+		if i := len(args) - 1; i < len(placeholders) {
+			p := placeholders[i]
+			if p.HasDefault && strings.HasPrefix(p.Default, toComplete) {
+				return []string{p.Default + "\t" + p.Name}, cobra.ShellCompDirectiveNoFileComp
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completePlaceholders completes the --set flag of exec with the names of
+// the placeholders of the bookmark given as the first argument.
+func completePlaceholders(bs store.BookmarkStoreLoader) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 || strings.Contains(toComplete, "=") {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		bookmarks, err := bs.Load()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		var completions []string
+		for _, p := range placeholder.Parse(bookmarks[args[0]].Summary()) {
+			if !strings.HasPrefix(p.Name, toComplete) {
+				continue
+			}
+			description := "placeholder"
+			if p.HasDefault {
+				description = fmt.Sprintf("default: %s", p.Default)
+			}
+			completions = append(completions, p.Name+"=\t"+description)
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+	}
+}
+
+// completeShells completes the name of a shell that runs commands.
+func completeShells(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return shellNames(), cobra.ShellCompDirectiveNoFileComp
+}
+
+// allTags returns every tag used by bookmarks, sorted.
+func allTags(bookmarks store.BookmarkContainer) []string {
+	var tags []string
+	for _, bookmark := range bookmarks {
+		tags = mergeTags(tags, bookmark.Tags)
+	}
+	return tags
+}
+
+// completeTags completes a tag with the tags that are already in use.
+func completeTags(bs store.BookmarkStoreLoader) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		bookmarks, err := bs.Load()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		var completions []string
+		for _, t := range allTags(bookmarks) {
+			if strings.HasPrefix(t, toComplete) {
+				completions = append(completions, t)
+			}
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeTagExpression completes the last tag of a tag expression.
+func completeTagExpression(bs store.BookmarkStoreLoader) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		bookmarks, err := bs.Load()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		i := strings.LastIndexAny(toComplete, "&|!() ") + 1
+		prefix, partial := toComplete[:i], toComplete[i:]
+		var completions []string
+		for _, t := range allTags(bookmarks) {
+			if strings.HasPrefix(t, partial) {
+				completions = append(completions, prefix+t)
+			}
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+	}
+}
+
+// completeTagArgs completes the name of a bookmark followed by tags. If
+// existing is true the tags of the bookmark are completed, otherwise the
+// tags used by other bookmarks.
+func completeTagArgs(bs store.BookmarkStoreLoader, existing bool) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		bookmarks, err := bs.Load()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		if len(args) == 0 {
+			return bookmarkCompletions(bookmarks, toComplete), cobra.ShellCompDirectiveNoFileComp
+		}
+		tags := bookmarks[args[0]].Tags
+		if !existing {
+			tags = allTags(bookmarks)
+		}
+		var completions []string
+		for _, t := range tags {
+			if !strings.HasPrefix(t, toComplete) || contains(args[1:], t) {
+				continue
+			}
+			if existing || !contains(bookmarks[args[0]].Tags, t) {
+				completions = append(completions, t)
+			}
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// contains reports whether s is in list.
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+}
@@ -0,0 +1,118 @@
+package cmd_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/henrikac/bookmark/cmd"
+	"github.com/henrikac/bookmark/internal/store"
+)
+
+func TestCompletion(t *testing.T) {
+	s := newTaggedBookmarkStore()
+	s.Bookmarks["logs"] = store.Bookmark{Command: "kubectl logs {{pod}} --tail {{lines:100}}", Description: "tail the\nlogs of a pod"}
+	root := cmd.NewRootCmd()
+	tagCmd := cmd.NewTagCmd()
+	tagCmd.AddCommand(cmd.TagAddCmd(s), cmd.TagRemoveCmd(s))
+	root.AddCommand(cmd.BookmarkAddCmd(s, cmd.NewPrompter()), cmd.BookmarkExecCmd(s, cmd.NewPrompter()), cmd.BookmarkRemoveCmd(s, cmd.NewPrompter()), cmd.BookmarkListCmd(s), tagCmd)
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{"names", []string{"exec", "p"}, []string{"pods\tkubectl get pods", "prod-pods\tkubectl --context prod get pods", "ps\tdocker ps", ":4"}},
+		{"description", []string{"remove", "lo"}, []string{"logs\ttail the logs of a pod", ":4"}},
+		{"one name", []string{"remove", "ps", ""}, []string{":4"}},
+		{"placeholder default", []string{"exec", "logs", "web", ""}, []string{"100\tlines", ":4"}},
+		{"set", []string{"exec", "logs", "--set", ""}, []string{"pod=\tplaceholder", "lines=\tdefault: 100", ":6"}},
+		{"tag flag", []string{"add", "--tag", "p"}, []string{"prod", ":4"}},
+		{"tag expression", []string{"list", "--tags", "k8s && !d"}, []string{"k8s && !docker", ":6"}},
+		{"tag add", []string{"tag", "add", "ps", ""}, []string{"k8s", "prod", ":4"}},
+		{"dash", []string{"exec", "logs", "--", "web", ""}, []string{":0"}},
+		{"tag remove", []string{"tag", "remove", "prod-pods", "k8s", ""}, []string{"prod", ":4"}},
+		{"output", []string{"list", "-o", ""}, []string{"json", "yaml", "csv", "tsv", "table", "template", ":4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := executeCommand(root, append([]string{"__complete"}, tt.args...)...)
+			if err != nil {
+				t.Fatalf("Error: %s", err)
+			}
+			lines := strings.Split(output, "\n")
+			i := 0
+			for ; i < len(lines) && !strings.HasPrefix(lines[i], ":"); i++ {
+			}
+			got := strings.Join(lines[:i+1], "\n")
+			if got != strings.Join(tt.expected, "\n") {
+				t.Errorf("Expected:\n%s\nGot:\n%s", strings.Join(tt.expected, "\n"), got)
+			}
+		})
+	}
+}
+
+func TestCompletionInstallCmd(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		shell    string
+		expected string
+	}{
+		{"bash", "__start_bookmark"},
+		{"zsh", "#compdef bookmark"},
+		{"fish", "complete -c bookmark"},
+		{"powershell", "Register-ArgumentCompleter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			root := cmd.NewRootCmd()
+			root.AddCommand(cmd.NewCompletionCmd())
+			path := filepath.Join(dir, tt.shell, "bookmark")
+			output, err := executeCommand(root, "completion", "install", "--shell", tt.shell, "--path", path)
+			if err != nil {
+				t.Fatalf("Error: %s", err)
+			}
+			if !strings.HasPrefix(output, "The "+tt.shell+" completion script was installed in "+path) {
+				t.Errorf("Unexpected output: %s", output)
+			}
+			script, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Error: %s", err)
+			}
+			if !strings.Contains(string(script), tt.expected) {
+				t.Errorf("Expected the script to contain %q", tt.expected)
+			}
+		})
+	}
+
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.NewCompletionCmd())
+	if _, err := executeCommand(root, "completion", "install", "--shell", "tcsh"); err == nil {
+		t.Error("Expected an error for an unsupported shell")
+	}
+}
+
+func TestCompletionInstallCmdDetectsShellEachRun(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.NewCompletionCmd())
+	tests := []struct {
+		shell    string
+		expected string
+	}{
+		{"/bin/zsh", filepath.Join(home, ".zsh", "completions", "_bookmark")},
+		{"/usr/bin/fish", filepath.Join(home, ".config", "fish", "completions", "bookmark.fish")},
+	}
+	for _, tt := range tests {
+		t.Setenv("SHELL", tt.shell)
+		if _, err := executeCommand(root, "completion", "install"); err != nil {
+			t.Fatalf("Error: %s", err)
+		}
+		if _, err := os.Stat(tt.expected); err != nil {
+			t.Errorf("Expected the script for %s to be installed in %s\nGot: %s", tt.shell, tt.expected, err)
+		}
+	}
+}
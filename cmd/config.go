@@ -22,7 +22,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 
+	"github.com/henrikac/bookmark/internal/store"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -31,6 +33,14 @@ import (
 type Config struct {
 	// StorePath specifies the path to where the user's bookmarks are stored.
 	StorePath string `json:"storePath"`
+	// DefaultShell specifies the shell that runs bookmarks that do not
+	// specify a shell themselves. If it is empty bash is used, or cmd on
+	// Windows.
+	DefaultShell string `json:"defaultShell"`
+	// Opener specifies the program that opens URL and file bookmarks. The
+	// URL or path is passed as the last argument. If it is empty xdg-open
+	// is used, open on macOS or the file protocol handler on Windows.
+	Opener string `json:"opener"`
 }
 
 var (
@@ -49,16 +59,36 @@ func NewConfigCmd() *cobra.Command {
 
 // NewConfigListCmd initializes a new config list command.
 func NewConfigListCmd() *cobra.Command {
-	return &cobra.Command{
+	var out output
+	listCmd := &cobra.Command{
 		Use:   "list",
 		Short: "List all your bookmark configurations",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := out.validate(); err != nil {
+				return err
+			}
 			configPath := viper.GetViper().ConfigFileUsed()
 			data, err := os.ReadFile(configPath)
 			if err != nil {
 				return err
 			}
+			if out.format != "" {
+				config := make(map[string]interface{})
+				if err := json.Unmarshal(data, &config); err != nil {
+					return err
+				}
+				keys := make([]string, 0, len(config))
+				for k := range config {
+					keys = append(keys, k)
+				}
+				sort.Strings(keys)
+				t := table{header: []string{"key", "value"}}
+				for _, k := range keys {
+					t.rows = append(t.rows, []string{k, fmt.Sprint(config[k])})
+				}
+				return out.write(cmd, config, t, []interface{}{config})
+			}
 			dst := &bytes.Buffer{}
 			err = json.Indent(dst, data, "", "    ")
 			if err != nil {
@@ -68,6 +98,8 @@ func NewConfigListCmd() *cobra.Command {
 			return nil
 		},
 	}
+	addOutputFlags(listCmd, &out)
+	return listCmd
 }
 
 func NewConfigSetCmd() *cobra.Command {
@@ -76,12 +108,9 @@ func NewConfigSetCmd() *cobra.Command {
 		Short: "Sets configuration <config> to the given <value>",
 		Args:  cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			config := viper.GetViper().GetString(args[0])
-			if config == "" {
-				return fmt.Errorf("unable to find the given config: \"%s\"", args[0])
-			}
 			switch args[0] {
 			case "storePath":
+				config := viper.GetViper().GetString(args[0])
 				newStorePath := args[1]
 				if !filepath.IsAbs(newStorePath) {
 					absPath, err := filepath.Abs(newStorePath)
@@ -110,12 +139,21 @@ func NewConfigSetCmd() *cobra.Command {
 						return err
 					}
 				}
-				err := os.Rename(config, filepath.Join(dir, file))
+				err := store.BookmarkFileStore{Path: config}.Move(filepath.Join(dir, file))
 				if err != nil {
 					return err
 				}
 				viper.GetViper().Set(args[0], newStorePath)
 				return viper.GetViper().WriteConfig()
+			case "defaultShell":
+				if err := validateShellName(args[1]); err != nil {
+					return err
+				}
+				viper.GetViper().Set(args[0], args[1])
+				return viper.GetViper().WriteConfig()
+			case "opener":
+				viper.GetViper().Set(args[0], args[1])
+				return viper.GetViper().WriteConfig()
 			default:
 				return fmt.Errorf("unable to find the given config: \"%s\"", args[0])
 			}
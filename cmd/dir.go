@@ -0,0 +1,292 @@
+// Copyright (C) 2022 Henrik A. Christensen
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/henrikac/bookmark/internal/search"
+	"github.com/henrikac/bookmark/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var (
+	bookmarkAddDirCmd = BookmarkAddDirCmd(bookmarkStore, prompter)
+	bookmarkMarkCmd   = BookmarkMarkCmd(bookmarkStore, prompter)
+	bookmarkJumpCmd   = BookmarkJumpCmd(bookmarkStore)
+)
+
+// BookmarkAddDirCmd initializes a new add-dir command.
+func BookmarkAddDirCmd(bs store.BookmarkStore, p Prompter) *cobra.Command {
+	var tags []string
+	addDirCmd := &cobra.Command{
+		Use:   "add-dir <bookmark> <dir>",
+		Short: "Add a new directory bookmark",
+		Long: `Add a new directory bookmark. Change to the directory with "bcd <bookmark>",
+defined by shell-init, or with cd "$(bookmark jump <bookmark>)".`,
+		Args: cobra.ExactArgs(2),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) == 1 {
+				return nil, cobra.ShellCompDirectiveFilterDirs
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return addDir(cmd, bs, p, args[0], args[1], tags)
+		},
+	}
+	addDirCmd.Flags().StringArrayVar(&tags, "tag", nil, "tag the bookmark, can be given multiple times")
+	_ = addDirCmd.RegisterFlagCompletionFunc("tag", completeTags(bs))
+	return addDirCmd
+}
+
+// BookmarkMarkCmd initializes a new mark command.
+func BookmarkMarkCmd(bs store.BookmarkStore, p Prompter) *cobra.Command {
+	var tags []string
+	markCmd := &cobra.Command{
+		Use:   "mark [bookmark]",
+		Short: "Bookmark the current directory",
+		Long: `Bookmark the current directory. The bookmark is named after the directory
+unless a name is given.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			name := filepath.Base(dir)
+			if len(args) > 0 {
+				name = args[0]
+			}
+			return addDir(cmd, bs, p, name, dir, tags)
+		},
+	}
+	markCmd.Flags().StringArrayVar(&tags, "tag", nil, "tag the bookmark, can be given multiple times")
+	_ = markCmd.RegisterFlagCompletionFunc("tag", completeTags(bs))
+	return markCmd
+}
+
+// addDir saves dir as a directory bookmark with the given name. The user is
+// asked with p before an existing bookmark is overridden.
+func addDir(cmd *cobra.Command, bs store.BookmarkStore, p Prompter, name, dir string, tags []string) error {
+	dir, err := absPath(dir)
+	if err != nil {
+		return err
+	}
+	expanded, err := expandPath(dir)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(expanded); err != nil || !info.IsDir() {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("\"%s\" is not a directory", expanded)
+	}
+	return addTarget(cmd, bs, p, name, store.KindDir, dir, tags)
+}
+
+// BookmarkJumpCmd initializes a new jump command.
+func BookmarkJumpCmd(bs store.BookmarkStoreLoadModifier) *cobra.Command {
+	return &cobra.Command{
+		Use:   "jump <query>...",
+		Short: "Print the directory of the best matching directory bookmark",
+		Long: `Print the directory of the best matching directory bookmark.
+
+A bookmark with the query as its name is always chosen. Otherwise every
+part of the query must be found in the name or the directory of the
+bookmark, and the bookmark used most often and most recently wins.
+Bookmarks of directories that no longer exist are removed.
+
+The bcd function defined by shell-init changes to the directory:
+
+  bcd proj`,
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completeExecArgs(bs, store.KindDir),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			bookmarks, err := pruneDirs(cmd, bs)
+			if err != nil {
+				return err
+			}
+			name, found := jumpTarget(bookmarks, args, time.Now())
+			if !found {
+				return fmt.Errorf("no directory bookmark matches \"%s\"", strings.Join(args, " "))
+			}
+			dir, err := expandPath(bookmarks[name].Target)
+			if err != nil {
+				return err
+			}
+			if err := recordUse(bs, name); err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), dir)
+			return nil
+		},
+	}
+}
+
+// pruneDirs removes the directory bookmarks of directories that no longer
+// exist and returns the remaining bookmarks.
+func pruneDirs(cmd *cobra.Command, bs store.BookmarkStoreLoadModifier) (store.BookmarkContainer, error) {
+	bookmarks, err := bs.Load()
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	targets := make(map[string]string)
+	for name, bookmark := range bookmarks {
+		if bookmark.Kind == store.KindDir && !dirExists(bookmark.Target) {
+			missing = append(missing, name)
+			targets[name] = bookmark.Target
+		}
+	}
+	if len(missing) == 0 {
+		return bookmarks, nil
+	}
+	sort.Strings(missing)
+	err = bs.Modify(func(current store.BookmarkContainer) error {
+		for _, name := range missing {
+			if bookmark, found := current[name]; found && bookmark.Kind == store.KindDir && !dirExists(bookmark.Target) {
+				delete(current, name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range missing {
+		cmd.Printf("Removed \"%s\", %s no longer exists\n", name, targets[name])
+		delete(bookmarks, name)
+	}
+	return bookmarks, nil
+}
+
+// dirExists reports whether dir exists. It only reports false if dir is
+// known to be missing, so bookmarks are not pruned because of e.g. a
+// permission error or an environment variable that is not set in the
+// current shell.
+func dirExists(dir string) bool {
+	if unsetEnv(dir) {
+		return true
+	}
+	expanded, err := expandPath(dir)
+	if err != nil {
+		return true
+	}
+	_, err = os.Stat(expanded)
+	return !errors.Is(err, os.ErrNotExist)
+}
+
+// unsetEnv reports whether path refers to an environment variable that is
+// unset or empty.
+func unsetEnv(path string) bool {
+	unset := false
+	os.Expand(path, func(name string) string {
+		if os.Getenv(name) == "" {
+			unset = true
+		}
+		return ""
+	})
+	return unset
+}
+
+// jumpTarget returns the name of the directory bookmark that best matches
+// the query given by args. A bookmark named like the query is chosen
+// first. Otherwise every part of the query has to match the name or the
+// directory of a bookmark and the matches are ranked by frecency.
+func jumpTarget(bookmarks store.BookmarkContainer, args []string, now time.Time) (string, bool) {
+	query := strings.Join(args, " ")
+	if bookmark, found := bookmarks[query]; found && bookmark.Kind == store.KindDir {
+		return query, true
+	}
+	matchers := make([]search.Matcher, len(args))
+	for i, arg := range args {
+		matchers[i], _ = search.NewMatcher(arg, search.Substring)
+	}
+	type candidate struct {
+		name     string
+		frecency float64
+		score    int
+	}
+	var candidates []candidate
+	for name, bookmark := range bookmarks {
+		if bookmark.Kind != store.KindDir {
+			continue
+		}
+		c := candidate{name: name, frecency: frecency(bookmark, now)}
+		for _, m := range matchers {
+			nameScore, _, nameOk := m.Match(name)
+			dirScore, _, dirOk := m.Match(bookmark.Target)
+			if !nameOk && !dirOk {
+				c.score = -1
+				break
+			}
+			if nameScore > dirScore {
+				c.score += nameScore
+			} else {
+				c.score += dirScore
+			}
+		}
+		if c.score >= 0 {
+			candidates = append(candidates, c)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.frecency != b.frecency {
+			return a.frecency > b.frecency
+		}
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		return a.name < b.name
+	})
+	return candidates[0].name, true
+}
+
+// frecency ranks a bookmark by how often and how recently it was used. The
+// number of uses is weighted by the time since the last use.
+func frecency(bookmark store.Bookmark, now time.Time) float64 {
+	if bookmark.LastUsedAt == nil {
+		return 0
+	}
+	age := now.Sub(*bookmark.LastUsedAt)
+	weight := 0.25
+	switch {
+	case age < time.Hour:
+		weight = 4
+	case age < 24*time.Hour:
+		weight = 2
+	case age < 7*24*time.Hour:
+		weight = 0.5
+	}
+	return float64(bookmark.RunCount) * weight
+}
+
+func init() {
+	rootCmd.AddCommand(bookmarkAddDirCmd)
+	rootCmd.AddCommand(bookmarkMarkCmd)
+	rootCmd.AddCommand(bookmarkJumpCmd)
+}
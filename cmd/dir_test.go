@@ -0,0 +1,190 @@
+package cmd_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henrikac/bookmark/cmd"
+	"github.com/henrikac/bookmark/internal/store"
+)
+
+func TestBookmarkAddDirCmd(t *testing.T) {
+	dir := t.TempDir()
+	s := newMemoryBookmarkStore()
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.BookmarkAddDirCmd(s, cmd.NewPrompter()))
+	output, err := executeCommand(root, "add-dir", "--tag", "src", "proj", dir)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if output != "New bookmark \"proj\" has been added successfully!\n" {
+		t.Errorf("Unexpected output: %s", output)
+	}
+	bookmark := s.Bookmarks["proj"]
+	if bookmark.Kind != store.KindDir || bookmark.Target != dir || bookmark.Command != "" || strings.Join(bookmark.Tags, ",") != "src" {
+		t.Errorf("Unexpected bookmark: %+v", bookmark)
+	}
+
+	root = cmd.NewRootCmd()
+	root.AddCommand(cmd.BookmarkAddDirCmd(s, cmd.NewPrompter()))
+	_, err = executeCommand(root, "add-dir", "missing", filepath.Join(dir, "missing"))
+	if err == nil || !strings.Contains(err.Error(), "is not a directory") {
+		t.Errorf("Expected an error for a missing directory\nGot: %v", err)
+	}
+}
+
+func TestBookmarkMarkCmd(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	// The temporary directory may be reached through a symlink.
+	if dir, err = os.Getwd(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		args []string
+		name string
+	}{
+		{[]string{"mark"}, filepath.Base(dir)},
+		{[]string{"mark", "here"}, "here"},
+	}
+	for _, tt := range tests {
+		s := newMemoryBookmarkStore()
+		root := cmd.NewRootCmd()
+		root.AddCommand(cmd.BookmarkMarkCmd(s, cmd.NewPrompter()))
+		if _, err := executeCommand(root, tt.args...); err != nil {
+			t.Fatalf("Error: %s", err)
+		}
+		if bookmark := s.Bookmarks[tt.name]; bookmark.Kind != store.KindDir || bookmark.Target != dir {
+			t.Errorf("Expected a bookmark of %s named %s\nGot: %v", dir, tt.name, s.Bookmarks)
+		}
+	}
+}
+
+func TestBookmarkJumpCmd(t *testing.T) {
+	base := t.TempDir()
+	for _, dir := range []string{"api", "web", "src/api", "src/apiary"} {
+		if err := os.MkdirAll(filepath.Join(base, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	recent := time.Now().Add(-time.Minute)
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	newStore := func() *memoryBookmarkStore {
+		s := newMemoryBookmarkStore()
+		s.Bookmarks["api"] = store.Bookmark{Kind: store.KindDir, Target: filepath.Join(base, "api"), RunCount: 10, LastUsedAt: &old}
+		s.Bookmarks["src-api"] = store.Bookmark{Kind: store.KindDir, Target: filepath.Join(base, "src/api"), RunCount: 2, LastUsedAt: &recent}
+		s.Bookmarks["apiary"] = store.Bookmark{Kind: store.KindDir, Target: filepath.Join(base, "src/apiary")}
+		s.Bookmarks["web"] = store.Bookmark{Kind: store.KindDir, Target: filepath.Join(base, "web")}
+		s.Bookmarks["deploy"] = store.Bookmark{Command: "make deploy web"}
+		return s
+	}
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"exact name", []string{"jump", "api"}, filepath.Join(base, "api")},
+		{"frecency", []string{"jump", "ap"}, filepath.Join(base, "src/api")},
+		{"every part matches", []string{"jump", "src", "ary"}, filepath.Join(base, "src/apiary")},
+		{"directory", []string{"jump", "eb"}, filepath.Join(base, "web")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStore()
+			root := cmd.NewRootCmd()
+			root.AddCommand(cmd.BookmarkJumpCmd(s))
+			output, err := executeCommand(root, tt.args...)
+			if err != nil {
+				t.Fatalf("Error: %s", err)
+			}
+			if output != tt.expected+"\n" {
+				t.Errorf("Expected: %s\nGot: %s", tt.expected, output)
+			}
+		})
+	}
+
+	s := newStore()
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.BookmarkJumpCmd(s))
+	if _, err := executeCommand(root, "jump", "deploy"); err == nil || err.Error() != "no directory bookmark matches \"deploy\"" {
+		t.Errorf("Expected no match for a command bookmark\nGot: %v", err)
+	}
+}
+
+func TestBookmarkJumpCmdPrunes(t *testing.T) {
+	base := t.TempDir()
+	if err := os.Mkdir(filepath.Join(base, "kept"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	s := newMemoryBookmarkStore()
+	s.Bookmarks["kept"] = store.Bookmark{Kind: store.KindDir, Target: filepath.Join(base, "kept")}
+	s.Bookmarks["gone"] = store.Bookmark{Kind: store.KindDir, Target: filepath.Join(base, "gone")}
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.BookmarkJumpCmd(s))
+	output, err := executeCommand(root, "jump", "kept")
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	expected := "Removed \"gone\", " + filepath.Join(base, "gone") + " no longer exists\n" + filepath.Join(base, "kept") + "\n"
+	if output != expected {
+		t.Errorf("Expected: %s\nGot: %s", expected, output)
+	}
+	if _, found := s.Bookmarks["gone"]; found {
+		t.Error("Expected the missing directory to be pruned")
+	}
+	if s.Bookmarks["kept"].RunCount != 1 {
+		t.Errorf("Expected the jump to count as a use\nGot: %+v", s.Bookmarks["kept"])
+	}
+}
+
+func TestBookmarkJumpCmdKeepsUnsetEnv(t *testing.T) {
+	base := t.TempDir()
+	t.Setenv("BOOKMARK_TEST_WORK", "")
+	os.Unsetenv("BOOKMARK_TEST_WORK")
+	s := newMemoryBookmarkStore()
+	s.Bookmarks["kept"] = store.Bookmark{Kind: store.KindDir, Target: base}
+	s.Bookmarks["work"] = store.Bookmark{Kind: store.KindDir, Target: filepath.Join("$BOOKMARK_TEST_WORK", "proj")}
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.BookmarkJumpCmd(s))
+	output, err := executeCommand(root, "jump", "kept")
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if output != base+"\n" {
+		t.Errorf("Expected: %s\nGot: %s", base+"\n", output)
+	}
+	if _, found := s.Bookmarks["work"]; !found {
+		t.Error("Expected a directory behind an unset variable to be kept")
+	}
+}
+
+func TestDirBookmarkExecAndPrint(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "my dir")
+	s := newMemoryBookmarkStore()
+	s.Bookmarks["proj"] = store.Bookmark{Kind: store.KindDir, Target: dir}
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.BookmarkExecCmd(s, cmd.NewPrompter()), cmd.BookmarkPickCmd(s, cmd.NewPrompter()))
+	_, err := executeCommand(root, "exec", "proj")
+	if err == nil || err.Error() != "\"proj\" is a dir bookmark, change to it with: bcd proj" {
+		t.Errorf("Expected an error for a directory bookmark\nGot: %v", err)
+	}
+	root.SetIn(strings.NewReader("1\n"))
+	output, err := executeCommand(root, "pick", "--print", "--shell", "bash", "proj")
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	expected := "cd '" + dir + "'\n"
+	if !strings.HasSuffix(output, expected) {
+		t.Errorf("Expected: %s\nGot: %s", expected, output)
+	}
+}
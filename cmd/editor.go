@@ -0,0 +1,458 @@
+// Copyright (C) 2022 Henrik A. Christensen
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/henrikac/bookmark/internal/shellwords"
+	"github.com/henrikac/bookmark/internal/store"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// editFormats are the formats bookmarks can be edited in.
+var editFormats = []string{"yaml", "toml"}
+
+// An editRecord is the part of a bookmark that is edited in an editor. The
+// usage statistics of the bookmark are kept as they are.
+type editRecord struct {
+	Kind        string            `yaml:"kind" toml:"kind"`
+	Command     string            `yaml:"command" toml:"command"`
+	Target      string            `yaml:"target" toml:"target"`
+	Description string            `yaml:"description" toml:"description"`
+	Shell       string            `yaml:"shell" toml:"shell"`
+	Cwd         string            `yaml:"cwd" toml:"cwd"`
+	Tags        []string          `yaml:"tags" toml:"tags"`
+	Env         map[string]string `yaml:"env" toml:"env"`
+}
+
+// newEditRecord returns the editable fields of bookmark.
+func newEditRecord(bookmark store.Bookmark) editRecord {
+	tags := bookmark.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	env := bookmark.Env
+	if env == nil {
+		env = map[string]string{}
+	}
+	return editRecord{
+		Kind:        bookmark.Kind.String(),
+		Command:     bookmark.Command,
+		Target:      bookmark.Target,
+		Description: bookmark.Description,
+		Shell:       bookmark.Shell,
+		Cwd:         bookmark.Cwd,
+		Tags:        tags,
+		Env:         env,
+	}
+}
+
+// validate returns every problem with the record.
+func (r editRecord) validate() []error {
+	var errs []error
+	kind, err := store.ParseKind(r.Kind)
+	switch {
+	case err != nil:
+		errs = append(errs, err)
+	case kind == store.KindCommand:
+		errs = r.validateCommand()
+	default:
+		if strings.TrimSpace(r.Target) == "" {
+			errs = append(errs, errors.New("target must not be empty"))
+		}
+		if r.Command != "" {
+			errs = append(errs, fmt.Errorf("a %s bookmark has no command", kind))
+		}
+		if kind == store.KindURL && strings.TrimSpace(r.Target) != "" {
+			if err := validateURL(r.Target); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if kind == store.KindScript && strings.TrimSpace(r.Target) != "" {
+			if _, err := scriptPath("", r.Target); err != nil {
+				errs = append(errs, fmt.Errorf("%w, the target of a script is the name of its file", err))
+			}
+		}
+	}
+	if err := validateTags(r.Tags); err != nil {
+		errs = append(errs, err)
+	}
+	for k := range r.Env {
+		if k == "" || strings.Contains(k, "=") {
+			errs = append(errs, fmt.Errorf("invalid environment variable: \"%s\"", k))
+		}
+	}
+	if _, err := absPath(r.Cwd); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// validateCommand returns every problem with the command of the record.
+func (r editRecord) validateCommand() []error {
+	var errs []error
+	if strings.TrimSpace(r.Command) == "" {
+		errs = append(errs, errors.New("command must not be empty"))
+	}
+	if r.Shell != "" {
+		if err := validateShellName(r.Shell); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := checkCommand(r.Command, resolveShell(r.Shell)); err != nil {
+		var syntaxErr *shellwords.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			err = fmt.Errorf("invalid command:\n%s", syntaxErr.Highlight(r.Command))
+		} else {
+			err = fmt.Errorf("invalid command: %w", err)
+		}
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// apply sets the edited fields of bookmark.
+func (r editRecord) apply(bookmark *store.Bookmark) {
+	bookmark.Kind, _ = store.ParseKind(r.Kind)
+	bookmark.Command = r.Command
+	bookmark.Target = r.Target
+	if bookmark.Kind != store.KindURL && bookmark.Kind != store.KindScript {
+		bookmark.Target, _ = absPath(r.Target)
+	}
+	bookmark.Description = r.Description
+	bookmark.Shell = r.Shell
+	bookmark.Cwd, _ = absPath(r.Cwd)
+	bookmark.Tags = nil
+	if len(r.Tags) > 0 {
+		bookmark.Tags = mergeTags(nil, r.Tags)
+	}
+	bookmark.Env = nil
+	if len(r.Env) > 0 {
+		bookmark.Env = r.Env
+	}
+}
+
+// kindNames returns the names of the kinds of bookmarks.
+func kindNames() []string {
+	names := make([]string, len(store.Kinds))
+	for i, k := range store.Kinds {
+		names[i] = k.String()
+	}
+	return names
+}
+
+// encodeEdit encodes v in the given edit format.
+func encodeEdit(format string, v interface{}) ([]byte, error) {
+	if format == "toml" {
+		return toml.Marshal(v)
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeEdit decodes data in the given edit format into v. Unknown fields
+// are reported as errors to catch misspelled field names.
+func decodeEdit(format string, data []byte, v interface{}) error {
+	if format == "toml" {
+		err := toml.NewDecoder(bytes.NewReader(data)).DisallowUnknownFields().Decode(v)
+		var decodeErr *toml.DecodeError
+		if errors.As(err, &decodeErr) {
+			row, col := decodeErr.Position()
+			return fmt.Errorf("line %d, column %d: %s", row, col, decodeErr.Error())
+		}
+		return err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// editorCommand returns the program and arguments of the user's editor,
+// taken from VISUAL or EDITOR.
+func editorCommand() ([]string, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	defaults := []string{"vi"}
+	if runtime.GOOS == "windows" {
+		defaults = []string{"notepad"}
+	}
+	argv, err := programCommand(editor, defaults)
+	if err != nil {
+		return nil, fmt.Errorf("invalid editor \"%s\": %w", editor, err)
+	}
+	return argv, nil
+}
+
+// programCommand returns the program and arguments configured by value, or
+// defaults if value is empty. value is either the path to a program, which
+// may contain spaces or backslashes, or a command line that is split into
+// words.
+func programCommand(value string, defaults []string) ([]string, error) {
+	if value == "" {
+		return defaults, nil
+	}
+	if _, err := exec.LookPath(value); err == nil {
+		return []string{value}, nil
+	}
+	argv, err := shellwords.Split(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(argv) == 0 {
+		return nil, errors.New("no program given")
+	}
+	return argv, nil
+}
+
+// editText lets the user edit text in their editor. The text is preceded
+// by header, written as comments. check is called with the saved text and
+// the editor is opened again, with the errors shown as comments, until
+// check succeeds. editText returns nil if the user saved an empty file.
+func editText(header string, text []byte, ext string, check func([]byte) []error) ([]byte, error) {
+	argv, err := editorCommand()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp("", "bookmark-*."+ext)
+	if err != nil {
+		return nil, err
+	}
+	path := f.Name()
+	f.Close()
+	defer os.Remove(path)
+	var errs []error
+	for {
+		content := comment(header)
+		if len(errs) > 0 {
+			content += "#\n# The file could not be saved:\n"
+			for _, err := range errs {
+				content += comment(err.Error())
+			}
+		}
+		if err := os.WriteFile(path, append([]byte(content), text...), 0600); err != nil {
+			return nil, err
+		}
+		editor := exec.Command(argv[0], append(argv[1:], path)...)
+		editor.Stdin = os.Stdin
+		editor.Stdout = os.Stdout
+		editor.Stderr = os.Stderr
+		if err := editor.Run(); err != nil {
+			return nil, fmt.Errorf("editor %s failed: %w", argv[0], err)
+		}
+		edited, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		text = stripComments(edited)
+		if len(bytes.TrimSpace(text)) == 0 {
+			return nil, nil
+		}
+		if errs = check(text); len(errs) == 0 {
+			return text, nil
+		}
+	}
+}
+
+// comment turns every line of s into a comment.
+func comment(s string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(s, "\n"), "\n") {
+		b.WriteString(strings.TrimRight("# "+line, " "))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// stripComments removes the comments that precede the text written by
+// editText. A shebang line is not a comment.
+func stripComments(text []byte) []byte {
+	for len(text) > 0 && text[0] == '#' && !bytes.HasPrefix(text, []byte("#!")) {
+		i := bytes.IndexByte(text, '\n')
+		if i < 0 {
+			return nil
+		}
+		text = text[i+1:]
+	}
+	return text
+}
+
+// editBookmark edits the bookmark with the given name in the user's editor.
+func editBookmark(cmd *cobra.Command, bs store.BookmarkStore, name, format string) error {
+	bookmarks, err := bs.Load()
+	if err != nil {
+		return err
+	}
+	bookmark, found := bookmarks[name]
+	if !found {
+		cmd.Printf("Unable to find bookmark: \"%s\"\n", name)
+		return nil
+	}
+	if bookmark.Kind == store.KindScript {
+		return editScript(cmd, bs, name, bookmark)
+	}
+	original, err := encodeEdit(format, newEditRecord(bookmark))
+	if err != nil {
+		return err
+	}
+	header := fmt.Sprintf(`Edit the bookmark "%s" and save the file to update it.
+Lines starting with # at the top are ignored and an empty file cancels the
+edit. kind is one of: %s. Commands have a command and other
+kinds a target. shell is empty for the default shell or one of:
+%s`, name, strings.Join(kindNames(), ", "), strings.Join(shellNames(), ", "))
+	var record editRecord
+	text, err := editText(header, original, format, func(text []byte) []error {
+		record = editRecord{}
+		if err := decodeEdit(format, text, &record); err != nil {
+			return []error{err}
+		}
+		return record.validate()
+	})
+	if err != nil {
+		return err
+	}
+	if text == nil {
+		cmd.Println("Edit cancelled, nothing was changed")
+		return nil
+	}
+	if bytes.Equal(text, original) {
+		cmd.Printf("Bookmark \"%s\" was not changed\n", name)
+		return nil
+	}
+	err = bs.Modify(func(bookmarks store.BookmarkContainer) error {
+		bookmark, found := bookmarks[name]
+		if !found {
+			return fmt.Errorf("bookmark \"%s\" was removed while you were editing it", name)
+		}
+		record.apply(&bookmark)
+		bookmarks[name] = bookmark
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	cmd.Printf("Bookmark \"%s\" has been updated successfully!\n", name)
+	return nil
+}
+
+// editAllBookmarks edits every bookmark in the user's editor. Bookmarks can
+// be added, changed, renamed and removed.
+func editAllBookmarks(cmd *cobra.Command, bs store.BookmarkStore, format string) error {
+	bookmarks, err := bs.Load()
+	if err != nil {
+		return err
+	}
+	records := make(map[string]editRecord, len(bookmarks))
+	for name, bookmark := range bookmarks {
+		records[name] = newEditRecord(bookmark)
+	}
+	original, err := encodeEdit(format, records)
+	if err != nil {
+		return err
+	}
+	header := fmt.Sprintf(`Edit your bookmarks and save the file to update them. Add, rename or
+remove entries to add, rename or remove bookmarks. Lines starting with #
+at the top are ignored and an empty file cancels the edit. kind is one of:
+%s. Commands have a command and other kinds a target. shell is
+empty for the default shell or one of: %s`, strings.Join(kindNames(), ", "), strings.Join(shellNames(), ", "))
+	var edited map[string]editRecord
+	text, err := editText(header, original, format, func(text []byte) []error {
+		edited = nil
+		if err := decodeEdit(format, text, &edited); err != nil {
+			return []error{err}
+		}
+		names := make([]string, 0, len(edited))
+		for name := range edited {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var errs []error
+		for _, name := range names {
+			if strings.TrimSpace(name) == "" {
+				errs = append(errs, errors.New("the name of a bookmark must not be empty"))
+			}
+			for _, err := range edited[name].validate() {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+		}
+		return errs
+	})
+	if err != nil {
+		return err
+	}
+	if text == nil {
+		cmd.Println("Edit cancelled, nothing was changed")
+		return nil
+	}
+	if bytes.Equal(text, original) {
+		cmd.Println("Your bookmarks were not changed")
+		return nil
+	}
+	var unused []string
+	err = bs.Modify(func(current store.BookmarkContainer) error {
+		var removed []store.Bookmark
+		// Bookmarks added by someone else while editing are kept.
+		for name := range bookmarks {
+			if _, found := edited[name]; !found {
+				removed = append(removed, current[name])
+				delete(current, name)
+			}
+		}
+		for name, record := range edited {
+			bookmark, found := current[name]
+			if !found {
+				bookmark.CreatedAt = time.Now()
+			}
+			removed = append(removed, bookmark)
+			record.apply(&bookmark)
+			current[name] = bookmark
+		}
+		unused = unusedScripts(current, removed...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := removeScripts(bs, unused); err != nil {
+		return err
+	}
+	cmd.Println("Your bookmarks have been updated successfully!")
+	return nil
+}
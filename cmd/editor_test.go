@@ -0,0 +1,130 @@
+package cmd_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/henrikac/bookmark/cmd"
+	"github.com/henrikac/bookmark/internal/store"
+)
+
+// fakeEditor makes a shell script the user's editor. The script is run with
+// the path of the file to edit as $1 and logs every file it is given.
+func fakeEditor(t *testing.T, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake editor is a shell script")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	editor := filepath.Join(dir, "editor")
+	content := "#!/bin/sh\ncat \"$1\" >> " + log + "\necho ----- >> " + log + "\n" + script + "\n"
+	if err := os.WriteFile(editor, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VISUAL", editor)
+	return log
+}
+
+func TestBookmarkEditCmdEditor(t *testing.T) {
+	log := fakeEditor(t, `
+if grep -q "could not be saved" "$1"; then
+  sed 's/^command: .*/command: kubectl get pods -A/' "$1" > "$1.new"
+else
+  sed 's/^command: .*/command: echo "pods/; s/^description: .*/description: list pods/; s/^tags: .*/tags: [k8s, pods]/' "$1" > "$1.new"
+fi
+mv "$1.new" "$1"`)
+	s := newMemoryBookmarkStore()
+	s.Bookmarks["pods"] = store.Bookmark{Command: "kubectl get pods", RunCount: 3}
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.BookmarkEditCmd(s))
+	output, err := executeCommand(root, "edit", "pods")
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if output != "Bookmark \"pods\" has been updated successfully!\n" {
+		t.Errorf("Unexpected output: %s", output)
+	}
+	expected := store.Bookmark{Command: "kubectl get pods -A", Description: "list pods", Tags: []string{"k8s", "pods"}, RunCount: 3}
+	if !reflect.DeepEqual(s.Bookmarks["pods"], expected) {
+		t.Errorf("Expected: %+v\nGot: %+v", expected, s.Bookmarks["pods"])
+	}
+	edits, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(edits), "-----") != 2 || !strings.Contains(string(edits), "# invalid command:\n# echo \"pods\n#      ^ unterminated double quote\n") {
+		t.Errorf("Expected the editor to be opened again with the error\nGot:\n%s", edits)
+	}
+}
+
+func TestBookmarkEditCmdEditorCancel(t *testing.T) {
+	fakeEditor(t, `: > "$1"`)
+	s := newMemoryBookmarkStore()
+	s.Bookmarks["pods"] = store.Bookmark{Command: "kubectl get pods"}
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.BookmarkEditCmd(s))
+	output, err := executeCommand(root, "edit", "--format", "toml", "pods")
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if output != "Edit cancelled, nothing was changed\n" {
+		t.Errorf("Unexpected output: %s", output)
+	}
+	if s.Bookmarks["pods"].Command != "kubectl get pods" {
+		t.Errorf("Expected the bookmark to be unchanged\nGot: %+v", s.Bookmarks["pods"])
+	}
+}
+
+func TestBookmarkEditCmdEditorAll(t *testing.T) {
+	fakeEditor(t, `cat > "$1" <<'EOF'
+[ls]
+command = 'ls'
+[pods]
+command = 'kubectl get pods'
+[ps]
+command = 'docker ps'
+EOF`)
+	s := newMemoryBookmarkStore()
+	s.Bookmarks["list"] = store.Bookmark{Command: "ls", RunCount: 2}
+	s.Bookmarks["ps"] = store.Bookmark{Command: "kubectl get pods"}
+	s.Bookmarks["logs"] = store.Bookmark{Command: "kubectl logs"}
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.BookmarkEditCmd(s))
+	output, err := executeCommand(root, "edit", "--all", "--format", "toml")
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if output != "Your bookmarks have been updated successfully!\n" {
+		t.Errorf("Unexpected output: %s", output)
+	}
+	got := make(map[string]string)
+	for name, bookmark := range s.Bookmarks {
+		got[name] = bookmark.Command
+	}
+	expected := map[string]string{"ls": "ls", "pods": "kubectl get pods", "ps": "docker ps"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected: %v\nGot: %v", expected, got)
+	}
+}
+
+func TestBookmarkEditCmdEditorDir(t *testing.T) {
+	dir := t.TempDir()
+	fakeEditor(t, `sed 's|^target: .*|target: `+dir+`|' "$1" > "$1.new"
+mv "$1.new" "$1"`)
+	s := newMemoryBookmarkStore()
+	s.Bookmarks["proj"] = store.Bookmark{Kind: store.KindDir, Target: "/old"}
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.BookmarkEditCmd(s))
+	if _, err := executeCommand(root, "edit", "proj"); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	expected := store.Bookmark{Kind: store.KindDir, Target: dir}
+	if !reflect.DeepEqual(s.Bookmarks["proj"], expected) {
+		t.Errorf("Expected: %+v\nGot: %+v", expected, s.Bookmarks["proj"])
+	}
+}
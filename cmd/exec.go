@@ -0,0 +1,217 @@
+// Copyright (C) 2022 Henrik A. Christensen
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/henrikac/bookmark/internal/placeholder"
+	"github.com/henrikac/bookmark/internal/shellwords"
+	"github.com/henrikac/bookmark/internal/store"
+	"github.com/spf13/cobra"
+)
+
+// positionalParamRe matches references to positional parameters such as
+// $@, $1 or ${2} in a shell script.
+var positionalParamRe = regexp.MustCompile(`\$(?:[@*#1-9]|\{[@*#1-9])`)
+
+// appendArgs makes the extra arguments given after "--" available to script.
+// Scripts that refer to their arguments, as matched by params, are expected
+// to receive the arguments separately and are returned unchanged. Otherwise
+// the quoted arguments are appended to the script, just like an alias.
+func appendArgs(script string, args []string, quote placeholder.QuoteFunc, params *regexp.Regexp) string {
+	if len(args) == 0 || params != nil && params.MatchString(script) {
+		return script
+	}
+	quoted := make([]string, 0, len(args)+1)
+	quoted = append(quoted, script)
+	for _, arg := range args {
+		quoted = append(quoted, quote(arg, 0))
+	}
+	return strings.Join(quoted, " ")
+}
+
+// validateCommand checks the syntax of command for the shell with the
+// given name. A syntax error is printed with the position of the error
+// highlighted. Only the syntax of POSIX shells is checked.
+func validateCommand(cmd *cobra.Command, command, shellName string) error {
+	err := checkCommand(command, shellName)
+	if err == nil {
+		return nil
+	}
+	var syntaxErr *shellwords.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		cmd.PrintErrln(syntaxErr.Highlight(command))
+	}
+	cmd.SilenceUsage = true
+	return fmt.Errorf("invalid command, use --no-validate to add it anyway: %w", err)
+}
+
+// checkCommand checks the syntax of command for the shell with the given
+// name.
+func checkCommand(command, shellName string) error {
+	switch {
+	case shellName == noShell:
+		_, err := shellwords.Split(command)
+		return err
+	case shells[shellName].posix && runtime.GOOS != "windows":
+		return shellwords.Check(command)
+	}
+	return nil
+}
+
+// directCommand returns the words of command if it is a simple command
+// whose program can be found, so it can be executed without a shell.
+func directCommand(command string) ([]string, bool) {
+	argv, ok := shellwords.Simple(command)
+	if !ok {
+		return nil, false
+	}
+	if _, err := exec.LookPath(argv[0]); err != nil {
+		return nil, false
+	}
+	return argv, true
+}
+
+// expandPath expands environment variables in path and replaces a leading
+// ~ with the user's home directory.
+func expandPath(path string) (string, error) {
+	path = os.ExpandEnv(path)
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, path[1:]), nil
+}
+
+// absPath makes a working directory given on the command line absolute.
+// Paths that start with ~ or refer to environment variables are kept as they
+// are, so they are expanded when the bookmark is executed.
+func absPath(path string) (string, error) {
+	if path == "" || strings.HasPrefix(path, "~") || strings.Contains(path, "$") {
+		return path, nil
+	}
+	return filepath.Abs(path)
+}
+
+// configureCommand applies the working directory and the environment
+// variables of bookmark to command.
+func configureCommand(command *exec.Cmd, name string, bookmark store.Bookmark) error {
+	if bookmark.Cwd != "" {
+		dir, err := expandPath(bookmark.Cwd)
+		if err != nil {
+			return err
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return fmt.Errorf("working directory \"%s\" of bookmark \"%s\" does not exist", dir, name)
+		}
+		command.Dir = dir
+	}
+	if len(bookmark.Env) > 0 {
+		keys := make([]string, 0, len(bookmark.Env))
+		for k := range bookmark.Env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		command.Env = os.Environ()
+		for _, k := range keys {
+			command.Env = append(command.Env, k+"="+bookmark.Env[k])
+		}
+	}
+	return nil
+}
+
+// killGracePeriod is how long a timed out bookmark is given to terminate
+// before it is killed.
+const killGracePeriod = 5 * time.Second
+
+// runCommand runs command and returns an *ExitError if it exits with a
+// non-zero exit code.
+//
+// Interrupt, termination and hangup signals received while the command runs
+// are forwarded to it. If timeout is positive the command is terminated
+// once it expires and an *ExitError with exit code 124 is returned.
+func runCommand(command *exec.Cmd, timeout time.Duration) error {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, forwardedSignals...)
+	defer signal.Stop(sigs)
+
+	cleanup, err := startCommand(command)
+	if err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- command.Wait()
+	}()
+
+	var expired, kill <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	timedOut := false
+	for {
+		select {
+		case sig := <-sigs:
+			_ = signalCommand(command, sig)
+		case <-expired:
+			timedOut = true
+			_ = terminateCommand(command)
+			timer := time.NewTimer(killGracePeriod)
+			defer timer.Stop()
+			kill = timer.C
+		case <-kill:
+			_ = killCommand(command)
+		case err := <-done:
+			cleanup()
+			if timedOut {
+				return &ExitError{Code: 124, Err: fmt.Errorf("timed out after %s", timeout)}
+			}
+			return commandExitError(err)
+		}
+	}
+}
+
+// commandExitError converts a non-zero exit of a command into an
+// *ExitError carrying the same exit code. A command killed by a signal exits
+// with 128 plus the signal number, like it would in a shell.
+func commandExitError(err error) error {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return err
+	}
+	code := exitErr.ExitCode()
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		code = 128 + int(status.Signal())
+	}
+	return &ExitError{Code: code, Err: exitErr}
+}
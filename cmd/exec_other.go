@@ -0,0 +1,54 @@
+// Copyright (C) 2022 Henrik A. Christensen
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+
+package cmd
+
+import (
+	"os"
+	"os/exec"
+)
+
+// forwardedSignals are the signals forwarded to an executed bookmark.
+var forwardedSignals = []os.Signal{os.Interrupt}
+
+// startCommand starts command. The returned function must be called once
+// the command has exited.
+func startCommand(command *exec.Cmd) (func(), error) {
+	if err := command.Start(); err != nil {
+		return nil, err
+	}
+	return func() {}, nil
+}
+
+// signalCommand forwards sig to command. An interrupt is delivered to the
+// command by the console itself, so it is not forwarded.
+func signalCommand(command *exec.Cmd, sig os.Signal) error {
+	if sig == os.Interrupt {
+		return nil
+	}
+	return command.Process.Kill()
+}
+
+// terminateCommand asks command to terminate.
+func terminateCommand(command *exec.Cmd) error {
+	return command.Process.Kill()
+}
+
+// killCommand kills command.
+func killCommand(command *exec.Cmd) error {
+	return command.Process.Kill()
+}
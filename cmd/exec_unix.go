@@ -0,0 +1,77 @@
+// Copyright (C) 2022 Henrik A. Christensen
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// forwardedSignals are the signals forwarded to an executed bookmark.
+var forwardedSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP}
+
+// startCommand starts command in its own process group. If the stdin of
+// command is the terminal and its foreground process group is ours, the new
+// process group is made the foreground process group so interactive
+// programs keep working. The returned function must be called once the
+// command has exited.
+func startCommand(command *exec.Cmd) (func(), error) {
+	command.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	tty := -1
+	if f, ok := command.Stdin.(*os.File); ok {
+		fd := int(f.Fd())
+		if pgrp, err := unix.IoctlGetInt(fd, unix.TIOCGPGRP); err == nil && pgrp == unix.Getpgrp() {
+			tty = fd
+			command.SysProcAttr.Foreground = true
+			command.SysProcAttr.Ctty = fd
+		}
+	}
+	if err := command.Start(); err != nil {
+		return nil, err
+	}
+	return func() {
+		if tty < 0 {
+			return
+		}
+		// Take the terminal back from the exited process group. Changing
+		// the foreground process group from the background raises SIGTTOU
+		// which has to be ignored while doing so.
+		signal.Ignore(syscall.SIGTTOU)
+		defer signal.Reset(syscall.SIGTTOU)
+		_ = unix.IoctlSetPointerInt(tty, unix.TIOCSPGRP, unix.Getpgrp())
+	}, nil
+}
+
+// signalCommand sends sig to the process group of command.
+func signalCommand(command *exec.Cmd, sig os.Signal) error {
+	return syscall.Kill(-command.Process.Pid, sig.(syscall.Signal))
+}
+
+// terminateCommand asks the process group of command to terminate.
+func terminateCommand(command *exec.Cmd) error {
+	return syscall.Kill(-command.Process.Pid, syscall.SIGTERM)
+}
+
+// killCommand kills the process group of command.
+func killCommand(command *exec.Cmd) error {
+	return syscall.Kill(-command.Process.Pid, syscall.SIGKILL)
+}
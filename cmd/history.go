@@ -0,0 +1,161 @@
+// Copyright (C) 2022 Henrik A. Christensen
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/henrikac/bookmark/internal/history"
+	"github.com/henrikac/bookmark/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var bookmarkHistoryCmd = BookmarkHistoryCmd(bookmarkStore)
+
+// BookmarkHistoryCmd initializes a new history command.
+func BookmarkHistoryCmd(bs store.BookmarkStoreLoadHistorian) *cobra.Command {
+	var name, since string
+	var failed bool
+	var limit int
+	var out output
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "List the executions of your bookmarks",
+		Long: `List the executions of your bookmarks, oldest first, with the time they
+started, how long they ran and their exit code.
+
+Every run of a bookmark by exec or the picker is recorded in a history file
+next to the store. Once the file grows beyond 1 MiB it is rotated, and the
+three most recently rotated files are kept.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := out.validate(); err != nil {
+				return err
+			}
+			var from time.Time
+			if since != "" {
+				var err error
+				if from, err = parseSince(since, time.Now()); err != nil {
+					return err
+				}
+			}
+			entries, err := history.New(bs.HistoryPath()).Entries()
+			if err != nil {
+				return err
+			}
+			records := make([]historyRecord, 0, len(entries))
+			for _, e := range entries {
+				if name != "" && e.Name != name || e.Time.Before(from) || failed && !e.Failed() {
+					continue
+				}
+				records = append(records, historyRecord(e))
+			}
+			if limit > 0 && len(records) > limit {
+				records = records[len(records)-limit:]
+			}
+			o := out
+			if o.format == "" {
+				if len(records) == 0 {
+					cmd.Println("No executions found")
+					return nil
+				}
+				o.format = "table"
+			}
+			return o.writeHistory(cmd, records)
+		},
+	}
+	historyCmd.Flags().StringVar(&name, "name", "", "only list executions of the bookmark with the given name")
+	historyCmd.Flags().StringVar(&since, "since", "", "only list executions since a duration ago or a date, e.g. 24h, 7d or 2022-06-01")
+	historyCmd.Flags().BoolVar(&failed, "failed", false, "only list executions that failed")
+	historyCmd.Flags().IntVarP(&limit, "limit", "n", 0, "only list the last n executions")
+	addOutputFlags(historyCmd, &out)
+	_ = historyCmd.RegisterFlagCompletionFunc("name", completeBookmarks(bs))
+	return historyCmd
+}
+
+// parseSince parses the value of --since: a duration before now such as
+// 24h or 7d, a date or a time in RFC 3339 format.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if strings.HasSuffix(value, "d") {
+		if days, err := strconv.Atoi(strings.TrimSuffix(value, "d")); err == nil && days >= 0 {
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since \"%s\", expected a duration such as 24h or 7d, or a date such as 2022-06-01", value)
+}
+
+// historyLog returns the history of the store bs, or nil if the store
+// does not keep one.
+func historyLog(bs store.BookmarkStoreHistorian) *history.Log {
+	if bs.HistoryPath() == "" {
+		return nil
+	}
+	return history.New(bs.HistoryPath())
+}
+
+// recordRun adds the execution described by e, which ended with err, to
+// the history of the store bs. A failure to record it is only reported, so
+// it never changes the outcome of the execution.
+func recordRun(cmd *cobra.Command, bs store.BookmarkStoreHistorian, e history.Entry, err error) {
+	l := historyLog(bs)
+	if l == nil {
+		return
+	}
+	if e.Cwd == "" {
+		e.Cwd, _ = os.Getwd()
+	}
+	var exitErr *ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		e.ExitCode = exitErr.Code
+		var cmdErr *exec.ExitError
+		if !errors.As(err, &cmdErr) {
+			e.Error = exitErr.Err.Error()
+		}
+	case err != nil:
+		e.ExitCode = -1
+		e.Error = err.Error()
+	}
+	if err := l.Append(e); err != nil {
+		cmd.PrintErrf("Unable to record the execution of \"%s\" in the history: %s\n", e.Name, err)
+	}
+}
+
+// recordFailure adds an execution of the bookmark with the given name that
+// failed with err before command could be started to the history of the
+// store bs.
+func recordFailure(cmd *cobra.Command, bs store.BookmarkStoreHistorian, name, command string, err error) {
+	recordRun(cmd, bs, history.Entry{Time: time.Now(), Name: name, Command: command}, err)
+}
+
+func init() {
+	rootCmd.AddCommand(bookmarkHistoryCmd)
+}
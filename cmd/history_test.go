@@ -0,0 +1,182 @@
+package cmd_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henrikac/bookmark/cmd"
+	"github.com/henrikac/bookmark/internal/history"
+	"github.com/henrikac/bookmark/internal/store"
+)
+
+func TestBookmarkExecCmdRecordsHistory(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the bookmarks are POSIX shell commands")
+	}
+	dir := t.TempDir()
+	s := newMemoryBookmarkStore()
+	s.History = filepath.Join(t.TempDir(), "bookmarks.history")
+	s.Bookmarks["greet"] = store.Bookmark{Command: "echo hello {{name}}", Cwd: dir}
+	s.Bookmarks["fail"] = store.Bookmark{Command: "exit 3", Shell: "sh"}
+	s.Bookmarks["slow"] = store.Bookmark{Command: "sleep 5", Shell: "sh"}
+	s.Bookmarks["args"] = store.Bookmark{Command: `echo "$@" | wc -w`, Shell: "sh"}
+	runs := [][]string{
+		{"exec", "greet", "world", "--", "again"},
+		{"exec", "args", "--", "a", "b"},
+		{"exec", "fail"},
+		{"exec", "--timeout", "10ms", "slow"},
+	}
+	start := time.Now()
+	for _, args := range runs {
+		root := cmd.NewRootCmd()
+		root.AddCommand(cmd.BookmarkExecCmd(s, cmd.NewPrompter()))
+		done := capture()
+		executeCommand(root, args...)
+		done()
+	}
+	entries, err := history.New(s.History).Entries()
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	wd, _ := filepath.Abs(".")
+	expected := []history.Entry{
+		{Name: "greet", Command: "echo hello world again", Cwd: dir},
+		{Name: "args", Command: `echo "$@" | wc -w`, Cwd: wd},
+		{Name: "fail", Command: "exit 3", Cwd: wd, ExitCode: 3},
+		{Name: "slow", Command: "sleep 5", Cwd: wd, ExitCode: 124, Error: "timed out after 10ms"},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %d entries\nGot: %+v", len(expected), entries)
+	}
+	for i, e := range entries {
+		x := expected[i]
+		if e.Name != x.Name || e.Command != x.Command || e.Cwd != x.Cwd || e.ExitCode != x.ExitCode || e.Error != x.Error {
+			t.Errorf("Expected: %+v\nGot: %+v", x, e)
+		}
+		if e.Time.Before(start.Add(-time.Second)) || e.DurationMs < 0 {
+			t.Errorf("Unexpected time or duration: %+v", e)
+		}
+	}
+}
+
+func TestBookmarkHistoryCmd(t *testing.T) {
+	t.Setenv("COLUMNS", "80")
+	s := newMemoryBookmarkStore()
+	s.History = filepath.Join(t.TempDir(), "bookmarks.history")
+	l := history.New(s.History)
+	now := time.Now().UTC().Truncate(time.Second)
+	entries := []history.Entry{
+		{Time: now.Add(-48 * time.Hour), Name: "deploy", Command: "make deploy", Cwd: "/src", DurationMs: 61000, ExitCode: 2},
+		{Time: now.Add(-2 * time.Hour), Name: "test", Command: "go test ./...", Cwd: "/src", DurationMs: 1500},
+		{Time: now.Add(-time.Hour), Name: "deploy", Command: "make deploy", Cwd: "/src", DurationMs: 30000},
+	}
+	for _, e := range entries {
+		if err := l.Append(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name     string
+		args     []string
+		expected []int
+	}{
+		{"all", nil, []int{0, 1, 2}},
+		{"name", []string{"--name", "deploy"}, []int{0, 2}},
+		{"failed", []string{"--failed"}, []int{0}},
+		{"since duration", []string{"--since", "1d"}, []int{1, 2}},
+		{"since date", []string{"--since", now.Add(-90 * time.Minute).Format(time.RFC3339)}, []int{2}},
+		{"limit", []string{"-n", "1", "--name", "deploy"}, []int{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := cmd.NewRootCmd()
+			root.AddCommand(cmd.BookmarkHistoryCmd(s))
+			output, err := executeCommand(root, append([]string{"history", "-o", "json"}, tt.args...)...)
+			if err != nil {
+				t.Fatalf("Error: %s", err)
+			}
+			var got []history.Entry
+			if err := json.Unmarshal([]byte(output), &got); err != nil {
+				t.Fatalf("Error: %s\n%s", err, output)
+			}
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected %d entries\nGot: %s", len(tt.expected), output)
+			}
+			for i, j := range tt.expected {
+				if !got[i].Time.Equal(entries[j].Time) || got[i].Name != entries[j].Name || got[i].DurationMs != entries[j].DurationMs {
+					t.Errorf("Expected: %+v\nGot: %+v", entries[j], got[i])
+				}
+			}
+		})
+	}
+
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.BookmarkHistoryCmd(s))
+	output, err := executeCommand(root, "history", "--failed")
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	expected := "TIME                  NAME    EXITCODE  DURATION  COMMAND\n" +
+		now.Add(-48*time.Hour).Format(time.RFC3339) + "  deploy  2         1m1s      make deploy\n"
+	if output != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, output)
+	}
+	output, err = executeCommand(root, "history", "--failed", "--name", "test")
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if output != "No executions found\n" {
+		t.Errorf("Expected: No executions found\nGot: %s", output)
+	}
+	if _, err := executeCommand(root, "history", "--since", "yesterday"); err == nil || !strings.Contains(err.Error(), "invalid --since") {
+		t.Errorf("Expected an error for an invalid --since\nGot: %v", err)
+	}
+}
+
+func TestBookmarkExecCmdRecordsFailures(t *testing.T) {
+	s := newMemoryBookmarkStore()
+	s.History = filepath.Join(t.TempDir(), "bookmarks.history")
+	s.Bookmarks["greet"] = store.Bookmark{Command: "echo hello {{name}}"}
+	s.Bookmarks["moved"] = store.Bookmark{Command: "echo hello", Cwd: filepath.Join(t.TempDir(), "missing")}
+	s.Bookmarks["noshell"] = store.Bookmark{Command: "nosuchprogram-bookmark --help", Shell: "none"}
+	s.Scripts = t.TempDir()
+	s.Bookmarks["gone"] = store.Bookmark{Kind: store.KindScript, Target: "gone"}
+	runs := [][]string{
+		{"exec", "greet"},
+		{"exec", "--shell", "nosuchshell", "greet", "world"},
+		{"exec", "moved"},
+		{"exec", "noshell"},
+		{"exec", "gone", "--", "-v"},
+	}
+	for _, args := range runs {
+		root := cmd.NewRootCmd()
+		root.AddCommand(cmd.BookmarkExecCmd(s, cmd.NewPrompter()))
+		root.SetIn(strings.NewReader(""))
+		if _, err := executeCommand(root, args...); err == nil {
+			t.Errorf("Expected %v to fail", args)
+		}
+	}
+	entries, err := history.New(s.History).Entries()
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	// Runs that fail before a command is built, e.g. because of a missing
+	// placeholder value or an unknown shell, are not recorded.
+	expected := []history.Entry{
+		{Name: "moved", Command: "echo hello"},
+		{Name: "noshell", Command: "nosuchprogram-bookmark --help"},
+		{Name: "gone", Command: "gone -v"},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %d entries\nGot: %+v", len(expected), entries)
+	}
+	for i, e := range entries {
+		if e.Name != expected[i].Name || e.Command != expected[i].Command || e.ExitCode != -1 || e.Error == "" {
+			t.Errorf("Expected a failed attempt: %+v\nGot: %+v", expected[i], e)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+// Copyright (C) 2022 Henrik A. Christensen
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/henrikac/bookmark/internal/placeholder"
+	"github.com/henrikac/bookmark/internal/store"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	bookmarkAddURLCmd  = BookmarkAddURLCmd(bookmarkStore, prompter)
+	bookmarkAddFileCmd = BookmarkAddFileCmd(bookmarkStore, prompter)
+	bookmarkOpenCmd    = BookmarkOpenCmd(bookmarkStore, prompter)
+)
+
+// BookmarkAddURLCmd initializes a new add-url command.
+func BookmarkAddURLCmd(bs store.BookmarkStore, p Prompter) *cobra.Command {
+	var tags []string
+	addURLCmd := &cobra.Command{
+		Use:   "add-url <bookmark> <url>",
+		Short: "Add a new URL bookmark",
+		Long: `Add a new URL bookmark, opened with "bookmark open <bookmark>".
+
+The URL may contain placeholders such as {{ticket}}, which are filled in
+when it is opened:
+
+  bookmark add-url jira "https://jira.example.com/browse/{{ticket}}"
+  bookmark open jira ABC-123`,
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: cobra.NoFileCompletions,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateURL(args[1]); err != nil {
+				cmd.SilenceUsage = true
+				return err
+			}
+			return addTarget(cmd, bs, p, args[0], store.KindURL, args[1], tags)
+		},
+	}
+	addURLCmd.Flags().StringArrayVar(&tags, "tag", nil, "tag the bookmark, can be given multiple times")
+	_ = addURLCmd.RegisterFlagCompletionFunc("tag", completeTags(bs))
+	return addURLCmd
+}
+
+// BookmarkAddFileCmd initializes a new add-file command.
+func BookmarkAddFileCmd(bs store.BookmarkStore, p Prompter) *cobra.Command {
+	var tags []string
+	addFileCmd := &cobra.Command{
+		Use:   "add-file <bookmark> <file>",
+		Short: "Add a new file bookmark",
+		Long: `Add a new file bookmark, opened with "bookmark open <bookmark>".
+
+The path may contain placeholders such as {{env}}, which are filled in when
+the file is opened.`,
+		Args: cobra.ExactArgs(2),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) == 1 {
+				return nil, cobra.ShellCompDirectiveDefault
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, err := absPath(args[1])
+			if err != nil {
+				return err
+			}
+			if len(placeholder.Parse(file)) == 0 {
+				expanded, err := expandPath(file)
+				if err != nil {
+					return err
+				}
+				if info, err := os.Stat(expanded); err != nil || info.IsDir() {
+					cmd.SilenceUsage = true
+					return fmt.Errorf("\"%s\" is not a file", expanded)
+				}
+			}
+			return addTarget(cmd, bs, p, args[0], store.KindFile, file, tags)
+		},
+	}
+	addFileCmd.Flags().StringArrayVar(&tags, "tag", nil, "tag the bookmark, can be given multiple times")
+	_ = addFileCmd.RegisterFlagCompletionFunc("tag", completeTags(bs))
+	return addFileCmd
+}
+
+// BookmarkOpenCmd initializes a new open command.
+func BookmarkOpenCmd(bs store.BookmarkStoreLoadModifier, p Prompter) *cobra.Command {
+	var opts openOptions
+	openCmd := &cobra.Command{
+		Use:   "open <bookmark> [placeholder values...]",
+		Short: "Open a URL, file or directory bookmark",
+		Long: `Open a URL, file or directory bookmark with the opener.
+
+The opener is set with "bookmark config set opener <program>". By default it
+is xdg-open, open on macOS or the file protocol handler on Windows.`,
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completeExecArgs(bs, store.KindURL, store.KindFile, store.KindDir),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bookmarks, err := bs.Load()
+			if err != nil {
+				return err
+			}
+			name := args[0]
+			bookmark, found := bookmarks[name]
+			if !found {
+				cmd.Printf("Unable to find bookmark: \"%s\"\n", name)
+				return nil
+			}
+			return openBookmark(cmd, bs, p, name, bookmark, args[1:], opts)
+		},
+	}
+	openCmd.Flags().StringArrayVar(&opts.sets, "set", nil, "set a placeholder value (key=value)")
+	openCmd.Flags().BoolVar(&opts.print, "print", false, "print the URL or path instead of opening it")
+	_ = openCmd.RegisterFlagCompletionFunc("set", completePlaceholders(bs))
+	return openCmd
+}
+
+// openOptions are the flags of the open command.
+type openOptions struct {
+	sets  []string
+	print bool
+}
+
+// openBookmark opens the target of bookmark with the opener. values fill in
+// the placeholders of the target and the user is asked for missing values
+// with p.
+func openBookmark(cmd *cobra.Command, bs store.BookmarkStoreModifier, p Prompter, name string, bookmark store.Bookmark, values []string, opts openOptions) error {
+	if bookmark.Kind == store.KindCommand || bookmark.Kind == store.KindScript {
+		cmd.SilenceUsage = true
+		return kindError(name, bookmark.Kind)
+	}
+	target, err := resolveTarget(bookmark, opts.sets, values, promptPlaceholder(cmd, p))
+	if err != nil {
+		return err
+	}
+	if opts.print {
+		if err := recordUse(bs, name); err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), target)
+		return nil
+	}
+	argv, err := openerCommand()
+	if err != nil {
+		return err
+	}
+	if err := recordUse(bs, name); err != nil {
+		return err
+	}
+	opener := exec.Command(argv[0], append(argv[1:], target)...)
+	opener.Stdin = cmd.InOrStdin()
+	opener.Stdout = os.Stdout
+	opener.Stderr = os.Stderr
+	if err := opener.Run(); err != nil {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("opener %s failed: %w", argv[0], err)
+	}
+	return nil
+}
+
+// resolveTarget fills in the placeholders of the target of bookmark, see
+// resolvePlaceholders. Values are escaped in URLs, and paths are expanded.
+func resolveTarget(bookmark store.Bookmark, sets, values []string, ask askFunc) (string, error) {
+	quote := literal
+	if bookmark.Kind == store.KindURL {
+		quote = urlQuote
+	}
+	target, err := resolvePlaceholders(bookmark.Target, sets, values, quote, ask)
+	if err != nil || bookmark.Kind == store.KindURL {
+		return target, err
+	}
+	return expandPath(target)
+}
+
+// openerLine returns the command line that opens the target of bookmark,
+// quoted with quote. See resolveTarget for sets and ask.
+func openerLine(bookmark store.Bookmark, sets []string, ask askFunc, quote placeholder.QuoteFunc) (string, error) {
+	target, err := resolveTarget(bookmark, sets, nil, ask)
+	if err != nil {
+		return "", err
+	}
+	argv, err := openerCommand()
+	if err != nil {
+		return "", err
+	}
+	words := make([]string, 0, len(argv)+1)
+	for _, arg := range append(argv, target) {
+		words = append(words, quote(arg, 0))
+	}
+	return strings.Join(words, " "), nil
+}
+
+// literal is a placeholder.QuoteFunc that inserts values as they are.
+func literal(value string, quote byte) string {
+	return value
+}
+
+// urlQuote is a placeholder.QuoteFunc for URLs. Values are escaped so they
+// can be used in both the path and the query of a URL.
+func urlQuote(value string, quote byte) string {
+	return strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+}
+
+// validateURL checks that u is an absolute URL once its placeholders are
+// filled in.
+func validateURL(u string) error {
+	values := make(map[string]string)
+	for _, p := range placeholder.Parse(u) {
+		values[p.Name] = "x"
+	}
+	expanded, err := placeholder.Expand(u, values, literal)
+	if err != nil {
+		return err
+	}
+	parsed, err := url.Parse(expanded)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if parsed.Scheme == "" {
+		return fmt.Errorf("invalid URL \"%s\": the scheme is missing, e.g. https://", u)
+	}
+	return nil
+}
+
+// openerCommand returns the program and arguments that open URLs and
+// files, taken from the opener configuration.
+func openerCommand() ([]string, error) {
+	var defaults []string
+	switch runtime.GOOS {
+	case "darwin":
+		defaults = []string{"open"}
+	case "windows":
+		defaults = []string{"rundll32", "url.dll,FileProtocolHandler"}
+	default:
+		defaults = []string{"xdg-open"}
+	}
+	opener := viper.GetViper().GetString("opener")
+	argv, err := programCommand(opener, defaults)
+	if err != nil {
+		return nil, fmt.Errorf("invalid opener \"%s\": %w", opener, err)
+	}
+	return argv, nil
+}
+
+// kindError returns the error for a bookmark that cannot be used by a
+// command because of its kind, telling how to use it instead.
+func kindError(name string, kind store.Kind) error {
+	var hint string
+	switch kind {
+	case store.KindCommand, store.KindScript:
+		hint = "run it with: bookmark exec " + name
+	case store.KindDir:
+		hint = "change to it with: bcd " + name
+	default:
+		hint = "open it with: bookmark open " + name
+	}
+	return fmt.Errorf("\"%s\" is a %s bookmark, %s", name, kind, hint)
+}
+
+func init() {
+	rootCmd.AddCommand(bookmarkAddURLCmd)
+	rootCmd.AddCommand(bookmarkAddFileCmd)
+	rootCmd.AddCommand(bookmarkOpenCmd)
+}
@@ -0,0 +1,144 @@
+package cmd_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/henrikac/bookmark/cmd"
+	"github.com/henrikac/bookmark/internal/store"
+	"github.com/spf13/viper"
+)
+
+func TestBookmarkAddURLCmd(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		err  string
+	}{
+		{"url", "https://grafana.example.com/d/api", ""},
+		{"placeholder", "https://jira.example.com/browse/{{ticket}}", ""},
+		{"placeholder in host", "https://{{host:localhost}}:8080/", ""},
+		{"missing scheme", "jira.example.com/browse/{{ticket}}", "invalid URL \"jira.example.com/browse/{{ticket}}\": the scheme is missing, e.g. https://"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newMemoryBookmarkStore()
+			root := cmd.NewRootCmd()
+			root.AddCommand(cmd.BookmarkAddURLCmd(s, cmd.NewPrompter()))
+			_, err := executeCommand(root, "add-url", "link", tt.url)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Errorf("Expected: %s\nGot: %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Error: %s", err)
+			}
+			expected := store.Bookmark{Kind: store.KindURL, Target: tt.url}
+			if got := s.Bookmarks["link"]; got.Kind != expected.Kind || got.Target != expected.Target {
+				t.Errorf("Expected: %+v\nGot: %+v", expected, got)
+			}
+		})
+	}
+}
+
+func TestBookmarkAddFileCmd(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "runbook.md")
+	if err := os.WriteFile(file, []byte("# Runbook\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := newMemoryBookmarkStore()
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.BookmarkAddFileCmd(s, cmd.NewPrompter()))
+	if _, err := executeCommand(root, "add-file", "runbook", file); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if got := s.Bookmarks["runbook"]; got.Kind != store.KindFile || got.Target != file {
+		t.Errorf("Unexpected bookmark: %+v", got)
+	}
+	if _, err := executeCommand(root, "add-file", "logs", filepath.Join(dir, "{{env}}.log")); err != nil {
+		t.Errorf("Expected a path with placeholders to be added\nGot: %s", err)
+	}
+	for _, path := range []string{dir, filepath.Join(dir, "missing.md")} {
+		if _, err := executeCommand(root, "add-file", "missing", path); err == nil || !strings.Contains(err.Error(), "is not a file") {
+			t.Errorf("Expected an error for %s\nGot: %v", path, err)
+		}
+	}
+}
+
+func TestBookmarkOpenCmdPrint(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"url", []string{"open", "--print", "jira", "ABC-123"}, "https://jira.example.com/browse/ABC-123?q=open"},
+		{"escaped", []string{"open", "--print", "--set", "q=a b&c", "jira", "x/y"}, "https://jira.example.com/browse/x%2Fy?q=a%20b%26c"},
+		{"file", []string{"open", "--print", "notes"}, filepath.Join(home, "notes", "today.md")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newMemoryBookmarkStore()
+			s.Bookmarks["jira"] = store.Bookmark{Kind: store.KindURL, Target: "https://jira.example.com/browse/{{ticket}}?q={{q:open}}"}
+			s.Bookmarks["notes"] = store.Bookmark{Kind: store.KindFile, Target: "~/notes/{{day:today}}.md"}
+			root := cmd.NewRootCmd()
+			root.AddCommand(cmd.BookmarkOpenCmd(s, cmd.NewPrompter()))
+			output, err := executeCommand(root, tt.args...)
+			if err != nil {
+				t.Fatalf("Error: %s", err)
+			}
+			if output != tt.expected+"\n" {
+				t.Errorf("Expected: %s\nGot: %s", tt.expected, output)
+			}
+		})
+	}
+}
+
+func TestBookmarkOpenCmdOpener(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake opener is a shell script")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	opener := filepath.Join(dir, "opener")
+	if err := os.WriteFile(opener, []byte("#!/bin/sh\necho \"$@\" >> "+log+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	viper.Set("opener", opener+" --new-tab")
+	defer viper.Set("opener", "")
+
+	s := newMemoryBookmarkStore()
+	s.Bookmarks["grafana"] = store.Bookmark{Kind: store.KindURL, Target: "https://grafana.example.com"}
+	s.Bookmarks["ls"] = store.Bookmark{Command: "ls"}
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.BookmarkOpenCmd(s, cmd.NewPrompter()), cmd.BookmarkExecCmd(s, cmd.NewPrompter()))
+	for _, args := range [][]string{{"open", "grafana"}, {"exec", "grafana"}} {
+		if _, err := executeCommand(root, args...); err != nil {
+			t.Fatalf("Error: %s", err)
+		}
+	}
+	opened, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "--new-tab https://grafana.example.com\n--new-tab https://grafana.example.com\n"
+	if string(opened) != expected {
+		t.Errorf("Expected: %q\nGot: %q", expected, opened)
+	}
+	if s.Bookmarks["grafana"].RunCount != 2 {
+		t.Errorf("Expected opening to count as a use\nGot: %+v", s.Bookmarks["grafana"])
+	}
+	_, err = executeCommand(root, "open", "ls")
+	if err == nil || err.Error() != "\"ls\" is a command bookmark, run it with: bookmark exec ls" {
+		t.Errorf("Expected an error for a command bookmark\nGot: %v", err)
+	}
+}
@@ -0,0 +1,420 @@
+// Copyright (C) 2022 Henrik A. Christensen
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/henrikac/bookmark/internal/store"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+)
+
+// outputFormats are the formats supported by --output.
+var outputFormats = []string{"json", "yaml", "csv", "tsv", "table", "template"}
+
+// An output holds the --output and --template flags of a command.
+type output struct {
+	format   string
+	template string
+}
+
+// addOutputFlags adds the --output and --template flags to c.
+func addOutputFlags(c *cobra.Command, o *output) {
+	c.Flags().StringVarP(&o.format, "output", "o", "", "output format: "+strings.Join(outputFormats, ", "))
+	c.Flags().StringVar(&o.template, "template", "", "Go template used by --output template, e.g. '{{.Name}}'")
+	_ = c.RegisterFlagCompletionFunc("output", cobra.FixedCompletions(outputFormats, cobra.ShellCompDirectiveNoFileComp))
+}
+
+// validate checks the flags before anything is written.
+func (o output) validate() error {
+	switch o.format {
+	case "", "json", "yaml", "csv", "tsv", "table":
+		if o.template != "" {
+			return fmt.Errorf("--template requires --output template")
+		}
+		return nil
+	case "template":
+		if o.template == "" {
+			return fmt.Errorf("--output template requires --template")
+		}
+		_, err := newOutputTemplate(o.template)
+		return err
+	default:
+		return fmt.Errorf("invalid output format \"%s\", expected one of: %s", o.format, strings.Join(outputFormats, ", "))
+	}
+}
+
+// A table is the tabular form of some output, used by the csv, tsv and
+// table formats.
+type table struct {
+	header []string
+	rows   [][]string
+	// columns are the names of the columns shown by the table format. All
+	// columns are shown if it is empty.
+	columns []string
+}
+
+// selectColumns returns a table with the columns shown by the table
+// format and upper case headers. Selected columns without any values are
+// left out.
+func (t table) selectColumns() table {
+	indices := make([]int, 0, len(t.header))
+	for i, h := range t.header {
+		if len(t.columns) == 0 {
+			indices = append(indices, i)
+			continue
+		}
+		for _, c := range t.columns {
+			if h == c && t.hasValues(i) {
+				indices = append(indices, i)
+			}
+		}
+	}
+	pick := func(row []string) []string {
+		picked := make([]string, len(indices))
+		for i, index := range indices {
+			picked[i] = row[index]
+		}
+		return picked
+	}
+	selected := table{header: pick(t.header)}
+	for i, h := range selected.header {
+		selected.header[i] = strings.ToUpper(h)
+	}
+	for _, row := range t.rows {
+		selected.rows = append(selected.rows, pick(row))
+	}
+	return selected
+}
+
+// hasValues reports whether any row has a value in the given column.
+func (t table) hasValues(column int) bool {
+	for _, row := range t.rows {
+		if row[column] != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// write writes data to the output of cmd in the selected format. data is
+// encoded by the json and yaml formats, t is written by the tabular
+// formats and the template is executed once for every item.
+func (o output) write(cmd *cobra.Command, data interface{}, t table, items []interface{}) error {
+	w := cmd.OutOrStdout()
+	switch o.format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(data)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(data); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(t.header)
+		cw.WriteAll(t.rows)
+		return cw.Error()
+	case "tsv":
+		for _, row := range append([][]string{t.header}, t.rows...) {
+			fields := make([]string, len(row))
+			for i, field := range row {
+				fields[i] = escapeTSV(field)
+			}
+			if _, err := fmt.Fprintln(w, strings.Join(fields, "\t")); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "table":
+		return writeTable(w, t.selectColumns(), terminalWidth(w))
+	case "template":
+		tmpl, err := newOutputTemplate(o.template)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, item); err != nil {
+				return err
+			}
+			if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+				buf.WriteByte('\n')
+			}
+			if _, err := buf.WriteTo(w); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid output format \"%s\"", o.format)
+}
+
+// newOutputTemplate parses the template of --template.
+func newOutputTemplate(text string) (*template.Template, error) {
+	return template.New("output").Funcs(template.FuncMap{
+		"join": strings.Join,
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(text)
+}
+
+// escapeTSV escapes the characters that cannot appear in a TSV field.
+func escapeTSV(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`).Replace(s)
+}
+
+// terminalWidth returns the width of the terminal w writes to. If w is not
+// a terminal the COLUMNS environment variable is used, or 80.
+func terminalWidth(w io.Writer) int {
+	if f, ok := w.(*os.File); ok {
+		if width, _, err := term.GetSize(int(f.Fd())); err == nil && width > 0 {
+			return width
+		}
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return 80
+}
+
+// tableGap is the space between the columns of a table.
+const tableGap = "  "
+
+// writeTable writes t as aligned columns. Columns are truncated, widest
+// first, until every line fits in width.
+func writeTable(w io.Writer, t table, width int) error {
+	rows := append([][]string{t.header}, t.rows...)
+	widths := make([]int, len(t.header))
+	for _, row := range rows {
+		for i := range row {
+			row[i] = escapeCell(row[i])
+			if n := utf8.RuneCountInString(row[i]); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	total := len(tableGap) * (len(widths) - 1)
+	for _, n := range widths {
+		total += n
+	}
+	for total > width {
+		widest := 0
+		for i, n := range widths {
+			if n > widths[widest] {
+				widest = i
+			}
+		}
+		if widths[widest] <= 3 {
+			break
+		}
+		widths[widest]--
+		total--
+	}
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cell = truncateCell(cell, widths[i])
+			if i < len(row)-1 {
+				cell += strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+			}
+			cells[i] = cell
+		}
+		line := strings.TrimRight(strings.Join(cells, tableGap), " ")
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// escapeCell makes control characters in a table cell visible, so they do
+// not break the layout of the table.
+func escapeCell(s string) string {
+	if strings.IndexFunc(s, unicode.IsControl) < 0 {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsControl(r) {
+			q := strconv.QuoteRune(r)
+			b.WriteString(q[1 : len(q)-1])
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// truncateCell shortens s to at most width characters, ending it with an
+// ellipsis if it was shortened.
+func truncateCell(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
+
+// A bookmarkRecord is a bookmark as it is written by --output.
+type bookmarkRecord struct {
+	Name        string            `json:"name" yaml:"name"`
+	Kind        string            `json:"kind" yaml:"kind"`
+	Command     string            `json:"command" yaml:"command"`
+	Target      string            `json:"target,omitempty" yaml:"target,omitempty"`
+	Description string            `json:"description,omitempty" yaml:"description,omitempty"`
+	Shell       string            `json:"shell,omitempty" yaml:"shell,omitempty"`
+	Cwd         string            `json:"cwd,omitempty" yaml:"cwd,omitempty"`
+	Env         map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
+	Tags        []string          `json:"tags,omitempty" yaml:"tags,omitempty"`
+	CreatedAt   *time.Time        `json:"createdAt,omitempty" yaml:"createdAt,omitempty"`
+	LastUsedAt  *time.Time        `json:"lastUsedAt,omitempty" yaml:"lastUsedAt,omitempty"`
+	RunCount    int               `json:"runCount" yaml:"runCount"`
+}
+
+func newBookmarkRecord(name string, b store.Bookmark) bookmarkRecord {
+	r := bookmarkRecord{
+		Name:        name,
+		Kind:        b.Kind.String(),
+		Command:     b.Command,
+		Target:      b.Target,
+		Description: b.Description,
+		Shell:       b.Shell,
+		Cwd:         b.Cwd,
+		Env:         b.Env,
+		Tags:        b.Tags,
+		LastUsedAt:  b.LastUsedAt,
+		RunCount:    b.RunCount,
+	}
+	if !b.CreatedAt.IsZero() {
+		createdAt := b.CreatedAt
+		r.CreatedAt = &createdAt
+	}
+	return r
+}
+
+// bookmarkColumns are the columns of bookmarks in tabular output.
+var bookmarkColumns = []string{"name", "command", "description", "tags", "shell", "cwd", "runCount", "lastUsedAt", "target", "kind"}
+
+// row returns the values of the bookmarkColumns of r.
+func (r bookmarkRecord) row() []string {
+	lastUsedAt := ""
+	if r.LastUsedAt != nil {
+		lastUsedAt = r.LastUsedAt.Format(time.RFC3339)
+	}
+	return []string{
+		r.Name,
+		r.Command,
+		r.Description,
+		strings.Join(r.Tags, ","),
+		r.Shell,
+		r.Cwd,
+		strconv.Itoa(r.RunCount),
+		lastUsedAt,
+		r.Target,
+		r.Kind,
+	}
+}
+
+// A searchRecord is a search result as it is written by --output.
+type searchRecord struct {
+	Score          int `json:"score" yaml:"score"`
+	bookmarkRecord `yaml:",inline"`
+}
+
+// writeBookmarks writes records in the selected format.
+func (o output) writeBookmarks(cmd *cobra.Command, records []bookmarkRecord) error {
+	t := table{header: bookmarkColumns, columns: []string{"name", "kind", "tags", "command", "target"}}
+	items := make([]interface{}, len(records))
+	for i, r := range records {
+		t.rows = append(t.rows, r.row())
+		items[i] = r
+	}
+	return o.write(cmd, records, t, items)
+}
+
+// writeSearchResults writes records in the selected format.
+func (o output) writeSearchResults(cmd *cobra.Command, records []searchRecord) error {
+	t := table{
+		header:  append([]string{"score"}, bookmarkColumns...),
+		columns: []string{"score", "name", "kind", "tags", "command", "target"},
+	}
+	items := make([]interface{}, len(records))
+	for i, r := range records {
+		t.rows = append(t.rows, append([]string{strconv.Itoa(r.Score)}, r.row()...))
+		items[i] = r
+	}
+	return o.write(cmd, records, t, items)
+}
+
+// A historyRecord is an execution of a bookmark as it is written by
+// --output.
+type historyRecord struct {
+	Time       time.Time `json:"time" yaml:"time"`
+	Name       string    `json:"name" yaml:"name"`
+	Command    string    `json:"command" yaml:"command"`
+	Cwd        string    `json:"cwd" yaml:"cwd"`
+	DurationMs int64     `json:"durationMs" yaml:"durationMs"`
+	ExitCode   int       `json:"exitCode" yaml:"exitCode"`
+	Error      string    `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// historyColumns are the columns of executions in tabular output.
+var historyColumns = []string{"time", "name", "exitCode", "duration", "command", "cwd", "error"}
+
+// row returns the values of the historyColumns of r.
+func (r historyRecord) row() []string {
+	return []string{
+		r.Time.Format(time.RFC3339),
+		r.Name,
+		strconv.Itoa(r.ExitCode),
+		(time.Duration(r.DurationMs) * time.Millisecond).String(),
+		r.Command,
+		r.Cwd,
+		r.Error,
+	}
+}
+
+// writeHistory writes records in the selected format.
+func (o output) writeHistory(cmd *cobra.Command, records []historyRecord) error {
+	t := table{header: historyColumns, columns: []string{"time", "name", "exitCode", "duration", "command"}}
+	items := make([]interface{}, len(records))
+	for i, r := range records {
+		t.rows = append(t.rows, r.row())
+		items[i] = r
+	}
+	return o.write(cmd, records, t, items)
+}
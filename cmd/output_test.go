@@ -0,0 +1,174 @@
+package cmd_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/henrikac/bookmark/cmd"
+	"github.com/henrikac/bookmark/internal/store"
+)
+
+func newOutputBookmarkStore() *memoryBookmarkStore {
+	s := newMemoryBookmarkStore()
+	createdAt := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	s.Bookmarks["fields"] = store.Bookmark{
+		Command:     `awk -F: '{print $1}' /etc/passwd`,
+		Description: "print\tusers, one per line",
+		Tags:        []string{"a", "b"},
+		CreatedAt:   createdAt,
+		RunCount:    2,
+	}
+	s.Bookmarks["html"] = store.Bookmark{Command: "echo '<b>&</b>'"}
+	return s
+}
+
+func TestBookmarkListCmdOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			"json",
+			[]string{"list", "-o", "json"},
+			`[
+  {
+    "name": "fields",
+    "kind": "command",
+    "command": "awk -F: '{print $1}' /etc/passwd",
+    "description": "print\tusers, one per line",
+    "tags": [
+      "a",
+      "b"
+    ],
+    "createdAt": "2022-06-01T12:00:00Z",
+    "runCount": 2
+  },
+  {
+    "name": "html",
+    "kind": "command",
+    "command": "echo '<b>&</b>'",
+    "runCount": 0
+  }
+]
+`,
+		},
+		{
+			"yaml",
+			[]string{"list", "--output", "yaml", "--tags", "a"},
+			`- name: fields
+  kind: command
+  command: 'awk -F: ''{print $1}'' /etc/passwd'
+  description: "print\tusers, one per line"
+  tags:
+    - a
+    - b
+  createdAt: 2022-06-01T12:00:00Z
+  runCount: 2
+`,
+		},
+		{
+			"csv",
+			[]string{"list", "-o", "csv"},
+			`name,command,description,tags,shell,cwd,runCount,lastUsedAt,target,kind
+fields,awk -F: '{print $1}' /etc/passwd,"print	users, one per line","a,b",,,2,,,command
+html,echo '<b>&</b>',,,,,0,,,command
+`,
+		},
+		{
+			"tsv",
+			[]string{"list", "-o", "tsv"},
+			"name\tcommand\tdescription\ttags\tshell\tcwd\trunCount\tlastUsedAt\ttarget\tkind\n" +
+				"fields\tawk -F: '{print $1}' /etc/passwd\tprint\\tusers, one per line\ta,b\t\t\t2\t\t\tcommand\n" +
+				"html\techo '<b>&</b>'\t\t\t\t\t0\t\t\tcommand\n",
+		},
+		{
+			"table",
+			[]string{"list", "-o", "table"},
+			`NAME    COMMAND                           TAGS  KIND
+fields  awk -F: '{print $1}' /etc/passwd  a,b   command
+html    echo '<b>&</b>'                         command
+`,
+		},
+		{
+			"template",
+			[]string{"list", "-o", "template", "--template", "{{.Name}}={{json .Tags}}"},
+			"fields=[\"a\",\"b\"]\nhtml=null\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("COLUMNS", "80")
+			s := newOutputBookmarkStore()
+			root := cmd.NewRootCmd()
+			root.AddCommand(cmd.BookmarkListCmd(s))
+			output, err := executeCommand(root, tt.args...)
+			if err != nil {
+				t.Errorf("Error: %s", err)
+			}
+			if output != tt.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", tt.expected, output)
+			}
+		})
+	}
+}
+
+func TestBookmarkListCmdTableTruncates(t *testing.T) {
+	t.Setenv("COLUMNS", "30")
+	s := newOutputBookmarkStore()
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.BookmarkListCmd(s))
+	output, err := executeCommand(root, "list", "-o", "table")
+	if err != nil {
+		t.Errorf("Error: %s", err)
+	}
+	expected := `NAME    COMMAND  TAGS  KIND
+fields  awk -F…  a,b   command
+html    echo '…        command
+`
+	if output != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, output)
+	}
+}
+
+func TestBookmarkSearchCmdOutput(t *testing.T) {
+	s := newOutputBookmarkStore()
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.BookmarkSearchCmd(s))
+	output, err := executeCommand(root, "search", "--substring", "-o", "template", "--template", "{{.Score}} {{.Name}}", "echo")
+	if err != nil {
+		t.Errorf("Error: %s", err)
+	}
+	if output != "89 html\n" {
+		t.Errorf("Expected: 89 html\nGot: %s", output)
+	}
+	output, err = executeCommand(root, "search", "-o", "json", "--template=", "nothing-matches")
+	if err != nil {
+		t.Errorf("Error: %s", err)
+	}
+	if output != "[]\n" {
+		t.Errorf("Expected: []\nGot: %s", output)
+	}
+}
+
+func TestOutputFlagErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"unknown format", []string{"list", "-o", "xml"}},
+		{"missing template", []string{"list", "-o", "template"}},
+		{"template without format", []string{"list", "--template", "{{.Name}}"}},
+		{"invalid template", []string{"list", "-o", "template", "--template", "{{.Name"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newOutputBookmarkStore()
+			root := cmd.NewRootCmd()
+			root.AddCommand(cmd.BookmarkListCmd(s))
+			if _, err := executeCommand(root, tt.args...); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
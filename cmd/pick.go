@@ -0,0 +1,275 @@
+// Copyright (C) 2022 Henrik A. Christensen
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/henrikac/bookmark/internal/picker"
+	"github.com/henrikac/bookmark/internal/placeholder"
+	"github.com/henrikac/bookmark/internal/search"
+	"github.com/henrikac/bookmark/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var bookmarkPickCmd = BookmarkPickCmd(bookmarkStore, prompter)
+
+// errCancelled is returned when the user cancels a prompt.
+var errCancelled = errors.New("cancelled")
+
+// BookmarkPickCmd initializes a new pick command.
+func BookmarkPickCmd(bs store.BookmarkStore, p Prompter) *cobra.Command {
+	var opts execOptions
+	var print bool
+	pickCmd := &cobra.Command{
+		Use:   "pick [query]",
+		Short: "Choose a bookmark to execute",
+		Long: `Choose a bookmark to execute in an interactive picker.
+
+Type to filter the bookmarks and use the arrow keys to select one. The
+selected bookmark is run with enter, its command is edited with ctrl-e,
+copied to the clipboard with ctrl-y and the bookmark is deleted with ctrl-x.
+
+With --print the command is printed with its placeholders filled in instead
+of being executed, which is what the widget of "bookmark shell-init" uses.
+
+If there is no terminal, the bookmarks are listed and you are prompted for
+the number of the bookmark to execute.`,
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeBookmarks(bs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, ok, err := pickBookmark(cmd, bs, p, strings.Join(args, ""))
+			if err != nil || !ok {
+				return err
+			}
+			bookmarks, err := bs.Load()
+			if err != nil {
+				return err
+			}
+			bookmark, found := bookmarks[name]
+			if !found {
+				cmd.Printf("Unable to find bookmark: \"%s\"\n", name)
+				return nil
+			}
+			if print {
+				return printBookmark(cmd, bs, p, name, bookmark, opts)
+			}
+			return execBookmark(cmd, bs, p, name, bookmark, nil, nil, opts)
+		},
+	}
+	pickCmd.Flags().BoolVar(&print, "print", false, "print the command instead of executing it")
+	pickCmd.Flags().StringArrayVar(&opts.sets, "set", nil, "set a placeholder value (key=value)")
+	pickCmd.Flags().StringVar(&opts.shell, "shell", "", "the shell that runs the command, or that placeholder values are quoted for with --print")
+	_ = pickCmd.RegisterFlagCompletionFunc("shell", completeShells)
+	return pickCmd
+}
+
+// pickBookmark lets the user choose a bookmark, starting with the given
+// filter query. The picker is shown if there is a terminal, otherwise the
+// user is asked for the number of a bookmark with p. It returns false if no
+// bookmark was chosen.
+func pickBookmark(cmd *cobra.Command, bs store.BookmarkStore, p Prompter, query string) (string, bool, error) {
+	bookmarks, err := bs.Load()
+	if err != nil {
+		return "", false, err
+	}
+	if len(bookmarks) == 0 {
+		cmd.Println("You have no saved bookmarks")
+		return "", false, nil
+	}
+	if hasTerminal(cmd) {
+		tty, err := picker.Open()
+		if err == nil {
+			name, ok, err := picker.Run(tty, bs, pickHandler{bs}, query)
+			if closeErr := tty.Close(); err == nil {
+				err = closeErr
+			}
+			return name, ok, err
+		}
+		if !errors.Is(err, picker.ErrNoTerminal) {
+			return "", false, err
+		}
+	}
+	return promptBookmark(cmd, p, bookmarks, query)
+}
+
+// hasTerminal reports whether the picker can be shown for cmd. The output
+// of the shell widget is captured, so it is enough that either the output
+// or the error output is a terminal. The picker is never shown with
+// --non-interactive.
+func hasTerminal(cmd *cobra.Command) bool {
+	if flagValue(cmd, "non-interactive") {
+		return false
+	}
+	return isTerminal(cmd.OutOrStdout()) || isTerminal(cmd.ErrOrStderr())
+}
+
+// printBookmark prints the command of bookmark with its placeholders filled
+// in and quoted for the shell given by opts. The user is asked for the
+// value of every placeholder not given by opts, prefilled with its default,
+// on the terminal if there is one. A directory bookmark is printed as a cd
+// command, a script bookmark as the path to its script and URL and file
+// bookmarks as the command of the opener. Printing a bookmark counts as a
+// use of it.
+func printBookmark(cmd *cobra.Command, bs store.BookmarkStore, pr Prompter, name string, bookmark store.Bookmark, opts execOptions) error {
+	shellName := resolveShell(opts.shell, bookmark.Shell)
+	if err := validateShellName(shellName); err != nil {
+		return err
+	}
+	if bookmark.Kind == store.KindDir {
+		dir, err := expandPath(bookmark.Target)
+		if err != nil {
+			return err
+		}
+		if err := recordUse(bs, name); err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), "cd "+shells[shellName].quote(dir, 0))
+		return nil
+	}
+	if bookmark.Kind == store.KindScript {
+		path, _, err := readScript(bs, name, bookmark)
+		if err != nil {
+			return err
+		}
+		if err := recordUse(bs, name); err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), shells[shellName].quote(path, 0))
+		return nil
+	}
+	ask := promptPlaceholder(cmd, pr)
+	if hasTerminal(cmd) {
+		tty, err := picker.Open()
+		if err == nil {
+			defer tty.Close()
+			ask = func(p placeholder.Placeholder) (string, bool, error) {
+				value, ok, err := picker.Prompt(tty, p.Name+": ", p.Default, bookmark.Summary())
+				if err == nil && !ok {
+					err = errCancelled
+				}
+				return value, true, err
+			}
+		} else if !errors.Is(err, picker.ErrNoTerminal) {
+			return err
+		}
+	}
+	var resolved string
+	var err error
+	if bookmark.Kind == store.KindCommand {
+		resolved, err = resolvePlaceholders(bookmark.Command, opts.sets, nil, shells[shellName].quote, ask)
+	} else {
+		resolved, err = openerLine(bookmark, opts.sets, ask, shells[shellName].quote)
+	}
+	if errors.Is(err, errCancelled) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := recordUse(bs, name); err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), resolved)
+	return nil
+}
+
+// promptBookmark lists the bookmarks matching query with a number and asks
+// the user for the number of the bookmark to choose with p.
+func promptBookmark(cmd *cobra.Command, p Prompter, bookmarks store.BookmarkContainer, query string) (string, bool, error) {
+	var matcher search.Matcher
+	if query != "" {
+		matcher, _ = search.NewMatcher(query, search.Fuzzy)
+	}
+	results := search.Search(bookmarks, matcher)
+	if len(results) == 0 {
+		cmd.Printf("Unable to find bookmark: \"%s\"\n", query)
+		return "", false, nil
+	}
+	for i, r := range results {
+		cmd.Printf("%d: %s: %s\n", i+1, r.Name, r.Bookmark.Summary())
+	}
+	input, err := p.Ask(cmd, fmt.Sprintf("Select a bookmark (1-%d): ", len(results)))
+	if err != nil {
+		return "", false, err
+	}
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return "", false, nil
+	}
+	n, err := strconv.Atoi(input)
+	if err != nil || n < 1 || n > len(results) {
+		return "", false, fmt.Errorf("invalid selection: \"%s\"", input)
+	}
+	return results[n-1].Name, true, nil
+}
+
+// pickHandler changes bookmarks on behalf of the picker.
+type pickHandler struct {
+	bs store.BookmarkStore
+}
+
+func (h pickHandler) Update(name, command string) error {
+	return h.bs.Modify(func(bookmarks store.BookmarkContainer) error {
+		bookmark, found := bookmarks[name]
+		if !found {
+			return fmt.Errorf("unable to find bookmark: \"%s\"", name)
+		}
+		switch bookmark.Kind {
+		case store.KindScript:
+			return fmt.Errorf("edit the script of \"%s\" with: bookmark edit %s", name, name)
+		case store.KindURL:
+			bookmark.Target = command
+			bookmarks[name] = bookmark
+			return nil
+		case store.KindDir, store.KindFile:
+			target, err := absPath(command)
+			if err != nil {
+				return err
+			}
+			bookmark.Target = target
+			bookmarks[name] = bookmark
+			return nil
+		}
+		if err := checkCommand(command, resolveShell(bookmark.Shell)); err != nil {
+			return fmt.Errorf("invalid command: %w", err)
+		}
+		bookmark.Command = command
+		bookmarks[name] = bookmark
+		return nil
+	})
+}
+
+func (h pickHandler) Delete(name string) error {
+	var unused []string
+	err := h.bs.Modify(func(bookmarks store.BookmarkContainer) error {
+		removed := bookmarks[name]
+		delete(bookmarks, name)
+		unused = unusedScripts(bookmarks, removed)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return removeScripts(h.bs, unused)
+}
+
+func init() {
+	rootCmd.AddCommand(bookmarkPickCmd)
+}
@@ -0,0 +1,118 @@
+package cmd_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/henrikac/bookmark/cmd"
+	"github.com/henrikac/bookmark/internal/store"
+)
+
+func TestBookmarkPickCmdPrompt(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		input    string
+		expected string
+		ran      string
+	}{
+		{
+			"choose",
+			[]string{"pick"},
+			"2\n",
+			"1: greet: echo hi\n2: hello: echo \"Hello world\"\nSelect a bookmark (1-2): ",
+			"Hello world\n",
+		},
+		{
+			"query",
+			[]string{"pick", "hlo"},
+			"1\n",
+			"1: hello: echo \"Hello world\"\nSelect a bookmark (1-1): ",
+			"Hello world\n",
+		},
+		{
+			"exec without bookmark",
+			[]string{"exec"},
+			"1\n",
+			"1: greet: echo hi\n2: hello: echo \"Hello world\"\nSelect a bookmark (1-2): ",
+			"hi\n",
+		},
+		{
+			"cancel",
+			[]string{"pick"},
+			"\n",
+			"1: greet: echo hi\n2: hello: echo \"Hello world\"\nSelect a bookmark (1-2): ",
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newMemoryBookmarkStore()
+			s.Bookmarks["hello"] = store.Bookmark{Command: "echo \"Hello world\""}
+			s.Bookmarks["greet"] = store.Bookmark{Command: "echo hi"}
+			root := cmd.NewRootCmd()
+			root.AddCommand(cmd.BookmarkPickCmd(s, cmd.NewPrompter()), cmd.BookmarkExecCmd(s, cmd.NewPrompter()))
+			root.SetIn(strings.NewReader(tt.input))
+			done := capture()
+			output, err := executeCommand(root, tt.args...)
+			if err != nil {
+				t.Errorf("Error: %s", err)
+			}
+			ran, err := done()
+			if err != nil {
+				t.Errorf("Error: %s", err)
+			}
+			if output != tt.expected {
+				t.Errorf("Expected: %q\nGot: %q", tt.expected, output)
+			}
+			if ran != tt.ran {
+				t.Errorf("Expected the bookmark to print: %q\nGot: %q", tt.ran, ran)
+			}
+		})
+	}
+}
+
+func TestBookmarkPickCmdInvalidSelection(t *testing.T) {
+	s := newMemoryBookmarkStore()
+	s.Bookmarks["hello"] = store.Bookmark{Command: "echo \"Hello world\""}
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.BookmarkPickCmd(s, cmd.NewPrompter()))
+	root.SetIn(strings.NewReader("3\n"))
+	_, err := executeCommand(root, "pick")
+	if err == nil || err.Error() != "invalid selection: \"3\"" {
+		t.Errorf("Expected an invalid selection error\nGot: %v", err)
+	}
+}
+
+func TestBookmarkPickCmdPrint(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		input    string
+		expected string
+	}{
+		{"default", []string{"pick", "--print", "grep"}, "1\n", "grep -rn 'TODO' .\n"},
+		{"set", []string{"pick", "--print", "--set", "file=src", "grep"}, "1\n", "grep -rn 'TODO' src\n"},
+		{"quote for shell", []string{"pick", "--print", "--shell", "fish", "--set", "file=it's", "grep"}, "1\n", "grep -rn 'TODO' 'it\\'s'\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newMemoryBookmarkStore()
+			s.Bookmarks["grep"] = store.Bookmark{Command: "grep -rn 'TODO' {{file:.}}"}
+			s.Bookmarks["greet"] = store.Bookmark{Command: "echo hi"}
+			root := cmd.NewRootCmd()
+			root.AddCommand(cmd.BookmarkPickCmd(s, cmd.NewPrompter()))
+			root.SetIn(strings.NewReader(tt.input))
+			output, err := executeCommand(root, tt.args...)
+			if err != nil {
+				t.Errorf("Error: %s", err)
+			}
+			if !strings.HasSuffix(output, tt.expected) {
+				t.Errorf("Expected the output to end with: %q\nGot: %q", tt.expected, output)
+			}
+			if s.Bookmarks["grep"].RunCount != 1 {
+				t.Errorf("Expected the bookmark to be counted as used\nGot: %d", s.Bookmarks["grep"].RunCount)
+			}
+		})
+	}
+}
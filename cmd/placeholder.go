@@ -0,0 +1,145 @@
+// Copyright (C) 2022 Henrik A. Christensen
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/henrikac/bookmark/internal/placeholder"
+	"github.com/spf13/cobra"
+)
+
+// An askFunc asks the user for the value of a placeholder that was not
+// given on the command line. It returns false if the default value of the
+// placeholder should be used.
+type askFunc func(p placeholder.Placeholder) (string, bool, error)
+
+// promptPlaceholder returns an askFunc that asks for the value of a
+// placeholder without a default value with pr.
+func promptPlaceholder(cmd *cobra.Command, pr Prompter) askFunc {
+	return func(p placeholder.Placeholder) (string, bool, error) {
+		if p.HasDefault {
+			return "", false, nil
+		}
+		value, err := pr.Ask(cmd, p.Name+": ")
+		if errors.Is(err, ErrNonInteractive) {
+			return "", false, fmt.Errorf("%w, use --set to give a value for placeholder \"%s\"", err, p.Name)
+		}
+		if err != nil {
+			return "", false, fmt.Errorf("missing value for placeholder \"%s\"", p.Name)
+		}
+		return value, true, nil
+	}
+}
+
+// resolvePlaceholders fills in the placeholders of command. Values are
+// taken from sets, given as key=value pairs, and then from args in the
+// order the placeholders first appear in command. ask is called for any
+// remaining placeholder.
+func resolvePlaceholders(
+	command string,
+	sets []string,
+	args []string,
+	quote placeholder.QuoteFunc,
+	ask askFunc,
+) (string, error) {
+	values, err := parseKeyValues(sets)
+	if err != nil {
+		return "", err
+	}
+	placeholders := placeholder.Parse(command)
+	known := make(map[string]bool, len(placeholders))
+	for _, p := range placeholders {
+		known[p.Name] = true
+	}
+	for name := range values {
+		if !known[name] {
+			return "", fmt.Errorf("unknown placeholder: \"%s\"", name)
+		}
+	}
+	for _, p := range placeholders {
+		if _, found := values[p.Name]; found {
+			continue
+		}
+		if len(args) > 0 {
+			values[p.Name] = args[0]
+			args = args[1:]
+			continue
+		}
+		value, ok, err := ask(p)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			values[p.Name] = value
+		}
+	}
+	if len(args) > 0 {
+		return "", fmt.Errorf("too many arguments: %s", strings.Join(args, " "))
+	}
+	return placeholder.Expand(command, values, quote)
+}
+
+// cmdQuote is a placeholder.QuoteFunc for the Windows command prompt.
+func cmdQuote(value string, quote byte) string {
+	escaped := strings.ReplaceAll(value, `"`, `""`)
+	if quote == '"' {
+		return escaped
+	}
+	return `"` + escaped + `"`
+}
+
+// parseKeyValues parses a list of key=value pairs.
+func parseKeyValues(pairs []string) (map[string]string, error) {
+	values := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, found := strings.Cut(pair, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("expected key=value but got: \"%s\"", pair)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// readLine reads a single line from r without the trailing line break.
+// It reads a byte at a time so that no input beyond the line is consumed.
+func readLine(r io.Reader) (string, error) {
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if buf[0] == '\n' {
+				break
+			}
+			line = append(line, buf[0])
+		}
+		if errors.Is(err, io.EOF) {
+			if len(line) == 0 {
+				return "", io.EOF
+			}
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSuffix(string(line), "\r"), nil
+}
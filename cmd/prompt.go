@@ -0,0 +1,104 @@
+// Copyright (C) 2022 Henrik A. Christensen
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// A Prompter asks the user questions on behalf of a command. Commands
+// never read answers from the process's standard input directly, so they
+// can be answered through the input of the command.
+type Prompter interface {
+	// Confirm asks a yes or no question and reports whether the user
+	// answered yes.
+	Confirm(cmd *cobra.Command, question string) (bool, error)
+	// Ask asks for a line of text and returns it without the line break.
+	Ask(cmd *cobra.Command, prompt string) (string, error)
+}
+
+// ErrNonInteractive is returned by a Prompter when an answer is needed but
+// the user cannot be asked.
+var ErrNonInteractive = errors.New("input is required but bookmark is running non-interactively")
+
+var prompter = NewPrompter()
+
+// NewPrompter returns a Prompter that reads the answers from the input of
+// a command. It honours the --yes, --no and --non-interactive flags and
+// does not prompt if the input is a file or pipe instead of a terminal.
+func NewPrompter() Prompter {
+	return inputPrompter{}
+}
+
+// inputPrompter is the Prompter returned by NewPrompter.
+type inputPrompter struct{}
+
+func (inputPrompter) Confirm(cmd *cobra.Command, question string) (bool, error) {
+	if flagValue(cmd, "yes") {
+		return true, nil
+	}
+	if flagValue(cmd, "no") {
+		return false, nil
+	}
+	if !isInteractive(cmd) {
+		cmd.SilenceUsage = true
+		return false, fmt.Errorf("%w, use --yes or --no to answer: %s", ErrNonInteractive, question)
+	}
+	cmd.Printf("%s (y/N)? ", question)
+	input, err := readLine(cmd.InOrStdin())
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	input = strings.ToLower(strings.TrimSpace(input))
+	return input == "y" || input == "yes", nil
+}
+
+func (inputPrompter) Ask(cmd *cobra.Command, prompt string) (string, error) {
+	if !isInteractive(cmd) {
+		cmd.SilenceUsage = true
+		return "", ErrNonInteractive
+	}
+	cmd.Print(prompt)
+	return readLine(cmd.InOrStdin())
+}
+
+// isInteractive reports whether the user can be asked questions. This is
+// not the case with --non-interactive or if the input of cmd is a file
+// that is not a terminal, e.g. in CI. Other inputs, such as one set with
+// SetIn, are always read.
+func isInteractive(cmd *cobra.Command) bool {
+	if flagValue(cmd, "non-interactive") {
+		return false
+	}
+	if f, ok := cmd.InOrStdin().(*os.File); ok {
+		return term.IsTerminal(int(f.Fd()))
+	}
+	return true
+}
+
+// flagValue returns the value of the boolean flag with the given name, or
+// false if cmd does not have the flag.
+func flagValue(cmd *cobra.Command, name string) bool {
+	value, err := cmd.Flags().GetBool(name)
+	return err == nil && value
+}
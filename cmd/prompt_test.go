@@ -0,0 +1,149 @@
+package cmd_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/henrikac/bookmark/cmd"
+	"github.com/henrikac/bookmark/internal/store"
+	"github.com/spf13/cobra"
+)
+
+func TestBookmarkRemoveCmdConfirm(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		input    string
+		expected string
+		removed  bool
+	}{
+		{"answer yes", []string{"remove", "test"}, "yes\n", "Are you sure you want to remove \"test\" (y/N)? \"test\" was removed successfully!\n", true},
+		{"answer no", []string{"remove", "test"}, "n\n", "Are you sure you want to remove \"test\" (y/N)? ", false},
+		{"no answer", []string{"remove", "test"}, "", "Are you sure you want to remove \"test\" (y/N)? ", false},
+		{"--yes", []string{"remove", "--yes", "test"}, "", "\"test\" was removed successfully!\n", true},
+		{"-y", []string{"-y", "remove", "test"}, "", "\"test\" was removed successfully!\n", true},
+		{"--no", []string{"remove", "--no", "test"}, "y\n", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newMemoryBookmarkStore()
+			s.Bookmarks["test"] = store.Bookmark{Command: "echo test"}
+			root := cmd.NewRootCmd()
+			root.AddCommand(cmd.BookmarkRemoveCmd(s, cmd.NewPrompter()))
+			root.SetIn(strings.NewReader(tt.input))
+			output, err := executeCommand(root, tt.args...)
+			if err != nil {
+				t.Errorf("Error: %s", err)
+			}
+			if output != tt.expected {
+				t.Errorf("Expected: %q\nGot: %q", tt.expected, output)
+			}
+			if _, found := s.Bookmarks["test"]; found == tt.removed {
+				t.Errorf("Expected removed: %t\nGot: %t", tt.removed, !found)
+			}
+		})
+	}
+}
+
+func TestBookmarkAddCmdConfirmOverride(t *testing.T) {
+	s := newMemoryBookmarkStore()
+	s.Bookmarks["test"] = store.Bookmark{Command: "echo old"}
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.BookmarkAddCmd(s, cmd.NewPrompter()))
+	root.SetIn(strings.NewReader("y\n"))
+	output, err := executeCommand(root, "add", "test", "echo new")
+	if err != nil {
+		t.Errorf("Error: %s", err)
+	}
+	expected := "test already exists: echo old\nDo you want to override it (y/N)? Bookmark \"test\" has been updated successfully!\n"
+	if output != expected {
+		t.Errorf("Expected: %q\nGot: %q", expected, output)
+	}
+	if s.Bookmarks["test"].Command != "echo new" {
+		t.Errorf("Expected: echo new\nGot: %s", s.Bookmarks["test"].Command)
+	}
+}
+
+func TestNonInteractive(t *testing.T) {
+	pipe, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pipe.Close()
+	w.Close()
+	tests := []struct {
+		name string
+		args []string
+		in   *os.File
+	}{
+		{"remove --non-interactive", []string{"remove", "--non-interactive", "test"}, nil},
+		{"remove from pipe", []string{"remove", "test"}, pipe},
+		{"add --non-interactive", []string{"add", "--non-interactive", "test", "echo new"}, nil},
+		{"exec --non-interactive", []string{"exec", "--non-interactive", "greet"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newMemoryBookmarkStore()
+			s.Bookmarks["test"] = store.Bookmark{Command: "echo old"}
+			s.Bookmarks["greet"] = store.Bookmark{Command: "echo hello {{name}}"}
+			root := cmd.NewRootCmd()
+			root.AddCommand(
+				cmd.BookmarkAddCmd(s, cmd.NewPrompter()),
+				cmd.BookmarkExecCmd(s, cmd.NewPrompter()),
+				cmd.BookmarkRemoveCmd(s, cmd.NewPrompter()),
+			)
+			if tt.in != nil {
+				root.SetIn(tt.in)
+			} else {
+				root.SetIn(strings.NewReader("y\n"))
+			}
+			_, err := executeCommand(root, tt.args...)
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			if !errors.Is(err, cmd.ErrNonInteractive) {
+				t.Errorf("Expected: %s\nGot: %s", cmd.ErrNonInteractive, err)
+			}
+			if s.Bookmarks["test"].Command != "echo old" {
+				t.Errorf("Expected the bookmarks to be unchanged\nGot: %+v", s.Bookmarks)
+			}
+		})
+	}
+}
+
+// answers is a Prompter with fixed answers.
+type answers struct {
+	confirm bool
+	asked   []string
+}
+
+func (a *answers) Confirm(c *cobra.Command, question string) (bool, error) {
+	a.asked = append(a.asked, question)
+	return a.confirm, nil
+}
+
+func (a *answers) Ask(c *cobra.Command, prompt string) (string, error) {
+	a.asked = append(a.asked, prompt)
+	return "world", nil
+}
+
+func TestPrompterInjection(t *testing.T) {
+	s := newMemoryBookmarkStore()
+	s.Bookmarks["test"] = store.Bookmark{Command: "echo old"}
+	p := &answers{confirm: true}
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.BookmarkRemoveCmd(s, p))
+	_, err := executeCommand(root, "remove", "--non-interactive", "test")
+	if err != nil {
+		t.Errorf("Error: %s", err)
+	}
+	if _, found := s.Bookmarks["test"]; found {
+		t.Error("Failed to remove bookmark")
+	}
+	expected := []string{"Are you sure you want to remove \"test\""}
+	if strings.Join(p.asked, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected: %q\nGot: %q", expected, p.asked)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright (C) 2022 Henrik A. Christensen
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/henrikac/bookmark/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var (
+	bookmarkRenameCmd = BookmarkRenameCmd(bookmarkStore)
+	bookmarkCopyCmd   = BookmarkCopyCmd(bookmarkStore)
+)
+
+// errNotFound is returned by a store modification if the bookmark to
+// change does not exist.
+var errNotFound = errors.New("bookmark not found")
+
+// BookmarkRenameCmd initializes a new rename command.
+func BookmarkRenameCmd(bs store.BookmarkStore) *cobra.Command {
+	var force bool
+	renameCmd := &cobra.Command{
+		Use:   "rename <bookmark> <new name>",
+		Short: "Rename a bookmark",
+		Long: `Rename a bookmark. Its description, tags, environment and usage statistics
+are kept.`,
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeBookmarks(bs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, newName := args[0], args[1]
+			err := transferBookmark(bs, name, newName, force, func(bookmarks store.BookmarkContainer, bookmark store.Bookmark) error {
+				delete(bookmarks, name)
+				bookmarks[newName] = bookmark
+				return nil
+			})
+			if errors.Is(err, errNotFound) {
+				cmd.Printf("Unable to find bookmark: \"%s\"\n", name)
+				return nil
+			}
+			if err != nil {
+				cmd.SilenceUsage = true
+				return err
+			}
+			cmd.Printf("Bookmark \"%s\" has been renamed to \"%s\"\n", name, newName)
+			return nil
+		},
+	}
+	renameCmd.Flags().BoolVarP(&force, "force", "f", false, "replace an existing bookmark with the new name")
+	return renameCmd
+}
+
+// BookmarkCopyCmd initializes a new copy command.
+func BookmarkCopyCmd(bs store.BookmarkStore) *cobra.Command {
+	var force bool
+	copyCmd := &cobra.Command{
+		Use:   "copy <bookmark> <new name>",
+		Short: "Copy a bookmark",
+		Long: `Copy a bookmark. The copy has the same command, description, tags and
+environment, but starts without usage statistics. The script of a script
+bookmark is copied to a new file.`,
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeBookmarks(bs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, newName := args[0], args[1]
+			err := transferBookmark(bs, name, newName, force, func(bookmarks store.BookmarkContainer, bookmark store.Bookmark) error {
+				c := copyBookmark(bookmark)
+				if c.Kind == store.KindScript {
+					file, err := copyScript(bs, name, bookmark)
+					if err != nil {
+						return err
+					}
+					c.Target = file
+				}
+				bookmarks[newName] = c
+				return nil
+			})
+			if errors.Is(err, errNotFound) {
+				cmd.Printf("Unable to find bookmark: \"%s\"\n", name)
+				return nil
+			}
+			if err != nil {
+				cmd.SilenceUsage = true
+				return err
+			}
+			cmd.Printf("Bookmark \"%s\" has been copied to \"%s\"\n", name, newName)
+			return nil
+		},
+	}
+	copyCmd.Flags().BoolVarP(&force, "force", "f", false, "replace an existing bookmark with the new name")
+	return copyCmd
+}
+
+// transferBookmark calls fn with the bookmark with the given name in a
+// single store modification, after checking that newName is free unless
+// force is true. The script of a replaced script bookmark is removed, and
+// so is a script written by fn for newName if the modification fails.
+func transferBookmark(bs store.BookmarkStore, name, newName string, force bool, fn func(store.BookmarkContainer, store.Bookmark) error) error {
+	if name == newName {
+		return fmt.Errorf("the new name of \"%s\" must be different", name)
+	}
+	var unused, added []string
+	err := bs.Modify(func(bookmarks store.BookmarkContainer) error {
+		bookmark, found := bookmarks[name]
+		if !found {
+			return errNotFound
+		}
+		replaced, exists := bookmarks[newName]
+		if exists && !force {
+			return fmt.Errorf("bookmark \"%s\" already exists, use --force to replace it", newName)
+		}
+		if err := fn(bookmarks, bookmark); err != nil {
+			return err
+		}
+		// Only a script fn wrote is removed if the store cannot be written,
+		// never the script of the bookmark it would have replaced.
+		added = addedScripts(bookmark, bookmarks[newName])
+		if exists {
+			unused = unusedScripts(bookmarks, replaced)
+		}
+		return nil
+	})
+	if err != nil {
+		_ = removeScripts(bs, added)
+		return err
+	}
+	return removeScripts(bs, unused)
+}
+
+// copyBookmark returns a copy of bookmark without usage statistics.
+func copyBookmark(bookmark store.Bookmark) store.Bookmark {
+	c := bookmark
+	c.Tags = append([]string(nil), bookmark.Tags...)
+	if bookmark.Env != nil {
+		c.Env = make(map[string]string, len(bookmark.Env))
+		for k, v := range bookmark.Env {
+			c.Env[k] = v
+		}
+	}
+	c.CreatedAt = time.Now()
+	c.LastUsedAt = nil
+	c.RunCount = 0
+	return c
+}
+
+func init() {
+	rootCmd.AddCommand(bookmarkRenameCmd)
+	rootCmd.AddCommand(bookmarkCopyCmd)
+}
@@ -0,0 +1,110 @@
+package cmd_test
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/henrikac/bookmark/cmd"
+	"github.com/henrikac/bookmark/internal/store"
+)
+
+func newRenameStore() *memoryBookmarkStore {
+	used := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	s := newMemoryBookmarkStore()
+	s.Bookmarks["pods"] = store.Bookmark{
+		Command:    "kubectl get pods",
+		Tags:       []string{"k8s"},
+		Env:        map[string]string{"KUBECONFIG": "~/.kube/dev"},
+		LastUsedAt: &used,
+		RunCount:   4,
+	}
+	s.Bookmarks["ps"] = store.Bookmark{Command: "docker ps"}
+	return s
+}
+
+func TestBookmarkRenameCmd(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+		names    []string
+	}{
+		{"rename", []string{"rename", "pods", "k8s-pods"}, "Bookmark \"pods\" has been renamed to \"k8s-pods\"\n", []string{"k8s-pods", "ps"}},
+		{"not found", []string{"rename", "svc", "services"}, "Unable to find bookmark: \"svc\"\n", []string{"pods", "ps"}},
+		{"force", []string{"rename", "--force", "pods", "ps"}, "Bookmark \"pods\" has been renamed to \"ps\"\n", []string{"ps"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newRenameStore()
+			pods := s.Bookmarks["pods"]
+			root := cmd.NewRootCmd()
+			root.AddCommand(cmd.BookmarkRenameCmd(s))
+			output, err := executeCommand(root, tt.args...)
+			if err != nil {
+				t.Fatalf("Error: %s", err)
+			}
+			if output != tt.expected {
+				t.Errorf("Expected: %q\nGot: %q", tt.expected, output)
+			}
+			if got := names(s.Bookmarks); !reflect.DeepEqual(got, tt.names) {
+				t.Errorf("Expected bookmarks: %v\nGot: %v", tt.names, got)
+			}
+			newName := tt.args[len(tt.args)-1]
+			if b, ok := s.Bookmarks[newName]; ok && !reflect.DeepEqual(b, pods) {
+				t.Errorf("Expected the bookmark to be kept as it was\nGot: %+v", b)
+			}
+		})
+	}
+}
+
+func TestBookmarkCopyCmd(t *testing.T) {
+	s := newRenameStore()
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.BookmarkCopyCmd(s))
+	output, err := executeCommand(root, "copy", "pods", "prod-pods")
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if output != "Bookmark \"pods\" has been copied to \"prod-pods\"\n" {
+		t.Errorf("Unexpected output: %q", output)
+	}
+	c := s.Bookmarks["prod-pods"]
+	if c.Command != "kubectl get pods" || c.RunCount != 0 || c.LastUsedAt != nil || c.CreatedAt.IsZero() {
+		t.Errorf("Expected a copy without usage statistics\nGot: %+v", c)
+	}
+	c.Env["KUBECONFIG"] = "~/.kube/prod"
+	c.Tags[0] = "prod"
+	if s.Bookmarks["pods"].Env["KUBECONFIG"] != "~/.kube/dev" || s.Bookmarks["pods"].Tags[0] != "k8s" {
+		t.Errorf("Expected the copy not to share its environment and tags with the original")
+	}
+}
+
+func TestBookmarkRenameAndCopyCmdConflict(t *testing.T) {
+	for _, command := range []string{"rename", "copy"} {
+		t.Run(command, func(t *testing.T) {
+			s := newRenameStore()
+			root := cmd.NewRootCmd()
+			root.AddCommand(cmd.BookmarkRenameCmd(s), cmd.BookmarkCopyCmd(s))
+			_, err := executeCommand(root, command, "pods", "ps")
+			expected := "bookmark \"ps\" already exists, use --force to replace it"
+			if err == nil || err.Error() != expected {
+				t.Errorf("Expected: %s\nGot: %v", expected, err)
+			}
+			if s.Bookmarks["ps"].Command != "docker ps" {
+				t.Errorf("Expected the existing bookmark to be kept\nGot: %+v", s.Bookmarks["ps"])
+			}
+		})
+	}
+}
+
+// names returns the sorted names of bookmarks.
+func names(bookmarks store.BookmarkContainer) []string {
+	var names []string
+	for name := range bookmarks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
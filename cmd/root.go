@@ -31,10 +31,32 @@ var (
 
 // NewRootCmd initializes a new root command.
 func NewRootCmd() *cobra.Command {
-	return &cobra.Command{
+	root := &cobra.Command{
 		Use:   "bookmark",
 		Short: "A CLI Bookmarker",
 	}
+	root.PersistentFlags().BoolP("yes", "y", false, "answer yes to every confirmation")
+	root.PersistentFlags().Bool("no", false, "answer no to every confirmation")
+	root.PersistentFlags().Bool("non-interactive", false, "never prompt for input, which is the default if the input is not a terminal")
+	root.MarkFlagsMutuallyExclusive("yes", "no")
+	return root
+}
+
+// An ExitError is returned by Execute when bookmark should exit with a
+// specific exit code, e.g. the exit code of an executed bookmark.
+type ExitError struct {
+	// Code is the exit code.
+	Code int
+	// Err is the error that caused the exit.
+	Err error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
 }
 
 // Execute executes the root command.
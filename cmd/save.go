@@ -0,0 +1,143 @@
+// Copyright (C) 2022 Henrik A. Christensen
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/henrikac/bookmark/internal/shellhist"
+	"github.com/henrikac/bookmark/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var bookmarkSaveCmd = BookmarkSaveCmd(bookmarkStore, prompter)
+
+// BookmarkSaveCmd initializes a new save command.
+func BookmarkSaveCmd(bs store.BookmarkStore, p Prompter) *cobra.Command {
+	var opts addOptions
+	var nth int
+	var fromStdin bool
+	var historyFile string
+	saveCmd := &cobra.Command{
+		Use:   "save <bookmark>",
+		Short: "Bookmark the previous command from your shell history",
+		Long: `Bookmark the previous command from your shell history.
+
+The command is read from the history file of bash, zsh or fish, so it does
+not have to be quoted again. The shell is detected from the SHELL environment
+variable unless --shell is given. Invocations of "bookmark save" itself are
+skipped.
+
+Bash writes its history file when it exits, unless e.g. "history -a" is
+added to PROMPT_COMMAND. The bookmark-save function of "bookmark shell-init"
+reads the history of the running shell instead and passes the command in
+with --from-stdin.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: cobra.NoFileCompletions,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var command string
+			var err error
+			if fromStdin {
+				command, err = readCommand(cmd.InOrStdin())
+			} else {
+				command, err = historyCommand(cmd, opts.shell, historyFile, nth)
+			}
+			if err != nil {
+				return err
+			}
+			cmd.Printf("Command: %s\n", command)
+			return addBookmark(cmd, bs, p, args[0], command, opts)
+		},
+	}
+	addFlags(saveCmd, bs, &opts)
+	saveCmd.Flags().Lookup("shell").Usage = "the shell whose history is read and that runs the command: " + strings.Join(shellNames(), ", ")
+	saveCmd.Flags().IntVarP(&nth, "nth", "n", 1, "save the nth-last command of the history")
+	saveCmd.Flags().BoolVar(&fromStdin, "from-stdin", false, "read the command from the standard input instead of the history")
+	saveCmd.Flags().StringVar(&historyFile, "history-file", "", "the history file to read instead of the shell's default")
+	saveCmd.MarkFlagsMutuallyExclusive("from-stdin", "nth")
+	saveCmd.MarkFlagsMutuallyExclusive("from-stdin", "history-file")
+	return saveCmd
+}
+
+// readCommand reads a command from r, without surrounding white space.
+func readCommand(r io.Reader) (string, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	command := strings.TrimSpace(string(b))
+	if command == "" {
+		return "", errors.New("no command was given on the standard input")
+	}
+	return command, nil
+}
+
+// historyCommand returns the nth-last command of the history of shell, or
+// of the user's shell if shell is empty.
+func historyCommand(cmd *cobra.Command, shell, path string, nth int) (string, error) {
+	if nth < 1 {
+		return "", fmt.Errorf("invalid value for --nth: %d", nth)
+	}
+	if shell == "" {
+		shell = detectShell()
+		if shell == "" {
+			return "", errors.New("unable to detect your shell, use --shell")
+		}
+	}
+	format, err := shellhist.FormatOf(shell)
+	if err != nil {
+		return "", err
+	}
+	if path == "" {
+		path, err = shellhist.DefaultPath(format)
+		if err != nil {
+			return "", err
+		}
+	}
+	commands, err := shellhist.ReadFile(path, format)
+	if err != nil {
+		return "", fmt.Errorf("unable to read the history of %s: %w", shell, err)
+	}
+	n := nth
+	for i := len(commands) - 1; i >= 0; i-- {
+		if isSaveCommand(commands[i], cmd.Root().Name()) {
+			continue
+		}
+		n--
+		if n == 0 {
+			return commands[i], nil
+		}
+	}
+	return "", fmt.Errorf("the history in %s has fewer than %d commands", path, nth)
+}
+
+// isSaveCommand reports whether command saves a command from the history
+// itself, using the program with the given name or the shell integration.
+func isSaveCommand(command, name string) bool {
+	fields := strings.Fields(command)
+	if len(fields) > 0 && fields[0] == "bookmark-save" {
+		return true
+	}
+	return len(fields) > 1 && filepath.Base(fields[0]) == name && fields[1] == "save"
+}
+
+func init() {
+	rootCmd.AddCommand(bookmarkSaveCmd)
+}
@@ -0,0 +1,74 @@
+package cmd_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/henrikac/bookmark/cmd"
+)
+
+func TestBookmarkSaveCmd(t *testing.T) {
+	history := filepath.Join(t.TempDir(), "history")
+	content := ": 1650000000:0;ls -al\n: 1650000001:0;echo \"a  b\" |\\\n  tr a-z A-Z\n: 1650000002:0;bookmark save shout\n"
+	if err := os.WriteFile(history, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		args     []string
+		input    string
+		expected string
+	}{
+		{"last", []string{"save", "--shell", "zsh", "--history-file", history, "shout"}, "", "echo \"a  b\" |\n  tr a-z A-Z"},
+		{"nth", []string{"save", "--shell", "zsh", "--history-file", history, "-n", "2", "list"}, "", "ls -al"},
+		{"stdin", []string{"save", "--from-stdin", "greet"}, "\t echo 'hello world'\n", "echo 'hello world'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newMemoryBookmarkStore()
+			root := cmd.NewRootCmd()
+			root.AddCommand(cmd.BookmarkSaveCmd(s, cmd.NewPrompter()))
+			root.SetIn(strings.NewReader(tt.input))
+			output, err := executeCommand(root, tt.args...)
+			if err != nil {
+				t.Fatalf("Error: %s", err)
+			}
+			name := tt.args[len(tt.args)-1]
+			if got := s.Bookmarks[name].Command; got != tt.expected {
+				t.Errorf("Expected: %q\nGot: %q", tt.expected, got)
+			}
+			if !strings.HasSuffix(output, "New bookmark \""+name+"\" has been added successfully!\n") {
+				t.Errorf("Unexpected output: %s", output)
+			}
+		})
+	}
+}
+
+func TestBookmarkSaveCmdErrors(t *testing.T) {
+	history := filepath.Join(t.TempDir(), "history")
+	if err := os.WriteFile(history, []byte("ls -al\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"too few", []string{"save", "--shell", "bash", "--history-file", history, "-n", "2", "x"}, "the history in " + history + " has fewer than 2 commands"},
+		{"unsupported shell", []string{"save", "--shell", "pwsh", "x"}, "unable to read the history of shell \"pwsh\", expected one of: bash, fish, zsh"},
+		{"empty stdin", []string{"save", "--from-stdin", "x"}, "no command was given on the standard input"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := cmd.NewRootCmd()
+			root.AddCommand(cmd.BookmarkSaveCmd(newMemoryBookmarkStore(), cmd.NewPrompter()))
+			root.SetIn(strings.NewReader(""))
+			_, err := executeCommand(root, tt.args...)
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("Expected: %s\nGot: %v", tt.expected, err)
+			}
+		})
+	}
+}
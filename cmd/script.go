@@ -0,0 +1,499 @@
+// Copyright (C) 2022 Henrik A. Christensen
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/henrikac/bookmark/internal/script"
+	"github.com/henrikac/bookmark/internal/shellwords"
+	"github.com/henrikac/bookmark/internal/store"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var bookmarkShowCmd = BookmarkShowCmd(bookmarkStore)
+
+// BookmarkShowCmd initializes a new show command.
+func BookmarkShowCmd(bs store.BookmarkStoreLoadScripter) *cobra.Command {
+	var color string
+	showCmd := &cobra.Command{
+		Use:   "show <bookmark>",
+		Short: "Show the command or script of a bookmark",
+		Long: `Show the command or script of a bookmark.
+
+Scripts are shown with line numbers. In shell scripts the lines that continue
+the command of the line above, after a backslash, inside quotes or in the
+body of a here-document, are marked with ┆ instead of │, and comments are
+dimmed when the output is colored.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeBookmarks(bs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			colored, err := useColor(cmd, color)
+			if err != nil {
+				return err
+			}
+			bookmarks, err := bs.Load()
+			if err != nil {
+				return err
+			}
+			name := args[0]
+			bookmark, found := bookmarks[name]
+			if !found {
+				cmd.Printf("Unable to find bookmark: \"%s\"\n", name)
+				return nil
+			}
+			if bookmark.Kind != store.KindScript {
+				fmt.Fprintln(cmd.OutOrStdout(), bookmark.Summary())
+				return nil
+			}
+			_, body, err := readScript(bs, name, bookmark)
+			if err != nil {
+				cmd.SilenceUsage = true
+				return err
+			}
+			return printScript(cmd.OutOrStdout(), body, colored)
+		},
+	}
+	showCmd.Flags().StringVar(&color, "color", "auto", "dim comments and line numbers: always, never or auto")
+	_ = showCmd.RegisterFlagCompletionFunc("color", cobra.FixedCompletions([]string{"auto", "always", "never"}, cobra.ShellCompDirectiveNoFileComp))
+	return showCmd
+}
+
+// printScript writes body to w with line numbers.
+func printScript(w io.Writer, body string, colored bool) error {
+	lines := script.Lines(body)
+	width := len(strconv.Itoa(len(lines)))
+	for _, line := range lines {
+		gutter := fmt.Sprintf("%*d │", width, line.Number)
+		if line.Continued {
+			gutter = fmt.Sprintf("%*d ┆", width, line.Number)
+		}
+		text := line.Text
+		if colored {
+			gutter = dimStart + gutter + highlightEnd
+			if line.Comment >= 0 {
+				text = text[:line.Comment] + dimStart + text[line.Comment:] + highlightEnd
+			}
+		}
+		if text != "" {
+			gutter += " " + text
+		}
+		if _, err := fmt.Fprintln(w, gutter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scriptDir returns the directory that holds the scripts of the store bs.
+func scriptDir(bs store.BookmarkStoreScripter) (string, error) {
+	dir := bs.ScriptDir()
+	if dir == "" {
+		return "", errors.New("the bookmark store does not support script bookmarks")
+	}
+	return dir, nil
+}
+
+// scriptPath returns the path to the script file with the given name in
+// dir. The name must not refer to a file outside of dir.
+func scriptPath(dir, file string) (string, error) {
+	if file == "" || file == "." || file == ".." || filepath.Base(file) != file {
+		return "", fmt.Errorf("invalid script file \"%s\"", file)
+	}
+	return filepath.Join(dir, file), nil
+}
+
+// readScript returns the path to the script of bookmark and the script.
+func readScript(bs store.BookmarkStoreScripter, name string, bookmark store.Bookmark) (string, string, error) {
+	dir, err := scriptDir(bs)
+	if err != nil {
+		return "", "", err
+	}
+	path, err := scriptPath(dir, bookmark.Target)
+	if err != nil {
+		return "", "", err
+	}
+	body, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", "", fmt.Errorf("the script of bookmark \"%s\" is missing: %w", name, err)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	return path, string(body), nil
+}
+
+// writeScript writes body to the script file with the given name and makes
+// it executable. If file is empty a new file named after the bookmark is
+// created, and removed again if body cannot be written to it. The name of
+// the written file is returned.
+func writeScript(bs store.BookmarkStoreScripter, name, file, body string) (string, error) {
+	dir, err := scriptDir(bs)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	created := file == ""
+	if created {
+		base := scriptFileName(name)
+		f, err := os.OpenFile(filepath.Join(dir, base), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0755)
+		if errors.Is(err, os.ErrExist) {
+			f, err = os.CreateTemp(dir, base+"-*")
+		}
+		if err != nil {
+			return "", err
+		}
+		file = filepath.Base(f.Name())
+		f.Close()
+	}
+	path, err := scriptPath(dir, file)
+	if err != nil {
+		return "", err
+	}
+	err = os.WriteFile(path, []byte(body), 0755)
+	if err == nil {
+		err = os.Chmod(path, 0755)
+	}
+	if err != nil {
+		if created {
+			os.Remove(path)
+		}
+		return "", err
+	}
+	return file, nil
+}
+
+// scriptFileName returns a file name for the script of the bookmark with
+// the given name.
+func scriptFileName(name string) string {
+	file := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return '_'
+	}, name)
+	if file = strings.TrimLeft(file, "."); file == "" {
+		return "script"
+	}
+	return file
+}
+
+// copyScript copies the script of bookmark to a new file for the bookmark
+// with the given name and returns the name of the new file.
+func copyScript(bs store.BookmarkStoreScripter, name string, bookmark store.Bookmark) (string, error) {
+	_, body, err := readScript(bs, name, bookmark)
+	if err != nil {
+		return "", err
+	}
+	return writeScript(bs, name, "", body)
+}
+
+// unusedScripts returns the script files of the removed bookmarks that no
+// bookmark in bookmarks refers to anymore.
+func unusedScripts(bookmarks store.BookmarkContainer, removed ...store.Bookmark) []string {
+	used := make(map[string]bool)
+	for _, bookmark := range bookmarks {
+		if bookmark.Kind == store.KindScript {
+			used[bookmark.Target] = true
+		}
+	}
+	var files []string
+	for _, bookmark := range removed {
+		if bookmark.Kind == store.KindScript && !used[bookmark.Target] {
+			files = append(files, bookmark.Target)
+			used[bookmark.Target] = true
+		}
+	}
+	return files
+}
+
+// addedScripts returns the script file of bookmark unless old uses it as
+// well, i.e. the file written for a change from old to bookmark.
+func addedScripts(old, bookmark store.Bookmark) []string {
+	if bookmark.Kind != store.KindScript || old.Kind == store.KindScript && old.Target == bookmark.Target {
+		return nil
+	}
+	return []string{bookmark.Target}
+}
+
+// removeScripts removes the given script files of the store bs.
+func removeScripts(bs store.BookmarkStoreScripter, files []string) error {
+	if len(files) == 0 {
+		return nil
+	}
+	dir, err := scriptDir(bs)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		path, err := scriptPath(dir, file)
+		if err != nil {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// addScript saves a script as a bookmark with the given name. The script is
+// written in the user's editor or read from the input, see scriptInput.
+// The user is asked with p before an existing bookmark is overridden.
+func addScript(cmd *cobra.Command, bs store.BookmarkStore, p Prompter, name string, opts addOptions) error {
+	vars, err := parseKeyValues(opts.env)
+	if err != nil {
+		return err
+	}
+	dir, err := absPath(opts.cwd)
+	if err != nil {
+		return err
+	}
+	if err := validateTags(opts.tags); err != nil {
+		return err
+	}
+	if _, err := scriptDir(bs); err != nil {
+		return err
+	}
+	bookmarks, err := bs.Load()
+	if err != nil {
+		return err
+	}
+	val, found := bookmarks[name]
+	if found {
+		cmd.Printf("%s already exists: %s\n", name, val.Summary())
+		override, err := p.Confirm(cmd, "Do you want to override it")
+		if err != nil || !override {
+			return err
+		}
+	}
+	var body string
+	if found && val.Kind == store.KindScript {
+		if _, body, err = readScript(bs, name, val); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	body, err = scriptInput(cmd, name, body, opts.noValidate)
+	if err != nil {
+		return err
+	}
+	if body == "" {
+		cmd.Println("The script is empty, nothing was changed")
+		return nil
+	}
+	// The script is written to a new file, so the bookmark keeps its old
+	// script if it cannot be saved.
+	return saveBookmark(cmd, bs, name, found, func(bookmark *store.Bookmark) error {
+		file, err := writeScript(bs, name, "", body)
+		if err != nil {
+			return err
+		}
+		bookmark.Kind = store.KindScript
+		bookmark.Target = file
+		bookmark.Command = ""
+		bookmark.Shell = ""
+		if cmd.Flags().Changed("cwd") {
+			bookmark.Cwd = dir
+		}
+		if cmd.Flags().Changed("env") {
+			bookmark.Env = vars
+		}
+		if cmd.Flags().Changed("tag") {
+			bookmark.Tags = mergeTags(nil, opts.tags)
+		}
+		return nil
+	})
+}
+
+// scriptInput returns the script of the bookmark with the given name. If
+// the input is a terminal the script is written in the user's editor,
+// starting from body, and otherwise it is read from the input. An empty
+// string is returned if the script is empty.
+func scriptInput(cmd *cobra.Command, name, body string, noValidate bool) (string, error) {
+	in, ok := cmd.InOrStdin().(*os.File)
+	if !ok || !term.IsTerminal(int(in.Fd())) {
+		data, err := io.ReadAll(cmd.InOrStdin())
+		if err != nil || len(bytes.TrimSpace(data)) == 0 {
+			return "", err
+		}
+		if errs := validateScript(string(data), noValidate); len(errs) > 0 {
+			cmd.SilenceUsage = true
+			return "", errs[0]
+		}
+		return string(data), nil
+	}
+	if flagValue(cmd, "non-interactive") {
+		cmd.SilenceUsage = true
+		return "", fmt.Errorf("%w, pipe the script to the command instead", ErrNonInteractive)
+	}
+	if body == "" {
+		body = "#!/usr/bin/env " + defaultInterpreter() + "\n\n"
+	}
+	header := fmt.Sprintf(`Write the script of the bookmark "%s" and save the file. The script must
+start with a shebang line such as #!/bin/sh. Lines starting with # above the
+shebang are ignored and an empty file cancels.`, name)
+	text, err := editText(header, []byte(body), scriptExt(body), func(text []byte) []error {
+		return validateScript(string(text), noValidate)
+	})
+	return string(text), err
+}
+
+// editScript edits the script of bookmark in the user's editor.
+func editScript(cmd *cobra.Command, bs store.BookmarkStoreScripter, name string, bookmark store.Bookmark) error {
+	_, body, err := readScript(bs, name, bookmark)
+	if err != nil {
+		return err
+	}
+	header := fmt.Sprintf(`Edit the script of the bookmark "%s" and save the file to update it.
+Lines starting with # above the shebang are ignored and an empty file
+cancels the edit.`, name)
+	text, err := editText(header, []byte(body), scriptExt(body), func(text []byte) []error {
+		return validateScript(string(text), false)
+	})
+	if err != nil {
+		return err
+	}
+	if text == nil {
+		cmd.Println("Edit cancelled, nothing was changed")
+		return nil
+	}
+	if string(text) == body {
+		cmd.Printf("Bookmark \"%s\" was not changed\n", name)
+		return nil
+	}
+	if _, err := writeScript(bs, name, bookmark.Target, string(text)); err != nil {
+		return err
+	}
+	cmd.Printf("Bookmark \"%s\" has been updated successfully!\n", name)
+	return nil
+}
+
+// validateScript returns the problems with body. A script must have a
+// shebang and unless noValidate is true the syntax of scripts run by a
+// POSIX shell is checked.
+func validateScript(body string, noValidate bool) []error {
+	if len(script.Interpreter(body)) == 0 {
+		return []error{errors.New("the script must start with a shebang line naming its interpreter, e.g. #!/bin/sh")}
+	}
+	name := script.Name(body)
+	if noValidate || name == noShell {
+		return nil
+	}
+	if err := checkCommand(body, name); err != nil {
+		var syntaxErr *shellwords.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			err = fmt.Errorf("invalid script:\n%s", syntaxErr.Highlight(body))
+		} else {
+			err = fmt.Errorf("invalid script: %w", err)
+		}
+		return []error{err}
+	}
+	return nil
+}
+
+// defaultInterpreter returns the interpreter of new scripts, the default
+// shell if it is a POSIX shell and sh otherwise.
+func defaultInterpreter() string {
+	name := resolveShell()
+	if sh, found := shells[name]; found && sh.posix && name != noShell {
+		return name
+	}
+	return "sh"
+}
+
+// scriptExt returns the file extension that makes editors recognize the
+// language of body.
+func scriptExt(body string) string {
+	name := script.Name(body)
+	switch {
+	case strings.HasPrefix(name, "python"):
+		return "py"
+	case name == "node":
+		return "js"
+	case name == "ruby":
+		return "rb"
+	case name == "perl":
+		return "pl"
+	case name == "pwsh":
+		return "ps1"
+	case name == "fish":
+		return "fish"
+	}
+	return "sh"
+}
+
+// scriptSummary returns the first line of the script of bookmark, or the
+// name of its file if the script cannot be read.
+func scriptSummary(bs store.BookmarkStoreScripter, name string, bookmark store.Bookmark) string {
+	_, body, err := readScript(bs, name, bookmark)
+	if err != nil {
+		return bookmark.Target
+	}
+	return script.Summary(body)
+}
+
+// scriptCommand returns the command that runs the script of bookmark with
+// the given arguments. Scripts are executed directly, except on Windows
+// where the interpreter named by the shebang is looked up in PATH.
+func scriptCommand(bs store.BookmarkStoreScripter, name string, bookmark store.Bookmark, args []string) (*exec.Cmd, error) {
+	path, body, err := readScript(bs, name, bookmark)
+	if err != nil {
+		return nil, err
+	}
+	if runtime.GOOS != "windows" {
+		return exec.Command(path, args...), nil
+	}
+	argv := script.Interpreter(body)
+	if len(argv) == 0 {
+		return nil, fmt.Errorf("the script of bookmark \"%s\" has no shebang", name)
+	}
+	program, err := exec.LookPath(filepath.Base(argv[0]))
+	if err != nil {
+		return nil, fmt.Errorf("interpreter \"%s\" for bookmark \"%s\" was not found in PATH", filepath.Base(argv[0]), name)
+	}
+	return exec.Command(program, append(append(argv[1:], path), args...)...), nil
+}
+
+// execScript runs the script of bookmark with the given arguments.
+func execScript(cmd *cobra.Command, bs store.BookmarkStore, name string, bookmark store.Bookmark, args []string, opts execOptions) error {
+	if opts.shell != "" || len(opts.sets) > 0 {
+		return fmt.Errorf("--shell and --set cannot be used with script bookmark \"%s\", pass arguments to the script instead", name)
+	}
+	command, err := scriptCommand(bs, name, bookmark, args)
+	if err != nil {
+		cmd.SilenceUsage = true
+		recordFailure(cmd, bs, name, shellwords.Join(append([]string{bookmark.Target}, args...)), err)
+		return err
+	}
+	return runBookmark(cmd, bs, name, bookmark, command, shellwords.Join(command.Args), opts.timeout)
+}
+
+func init() {
+	rootCmd.AddCommand(bookmarkShowCmd)
+}
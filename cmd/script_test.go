@@ -0,0 +1,266 @@
+package cmd_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/henrikac/bookmark/cmd"
+	"github.com/henrikac/bookmark/internal/store"
+)
+
+// newScriptStore returns a store with a script directory and a script
+// bookmark named deploy.
+func newScriptStore(t *testing.T) *memoryBookmarkStore {
+	t.Helper()
+	s := newMemoryBookmarkStore()
+	s.Scripts = t.TempDir()
+	body := "#!/bin/sh\n# Deploy the api\nfor env in \"$@\"; do\n  echo \"deploying $env\" \\\n    >> \"$LOG\"\ndone\n"
+	if err := os.WriteFile(filepath.Join(s.Scripts, "deploy"), []byte(body), 0755); err != nil {
+		t.Fatal(err)
+	}
+	s.Bookmarks["deploy"] = store.Bookmark{Kind: store.KindScript, Target: "deploy"}
+	return s
+}
+
+func TestBookmarkAddCmdScript(t *testing.T) {
+	s := newMemoryBookmarkStore()
+	s.Scripts = filepath.Join(t.TempDir(), "scripts")
+	body := "#!/usr/bin/env bash\n\necho one\necho two\n"
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.BookmarkAddCmd(s, cmd.NewPrompter()), cmd.BookmarkListCmd(s))
+	root.SetIn(strings.NewReader(body))
+	output, err := executeCommand(root, "add", "--script", "--tag", "ops", "two lines")
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if output != "New bookmark \"two lines\" has been added successfully!\n" {
+		t.Errorf("Unexpected output: %s", output)
+	}
+	bookmark := s.Bookmarks["two lines"]
+	if bookmark.Kind != store.KindScript || bookmark.Target != "two_lines" || strings.Join(bookmark.Tags, ",") != "ops" {
+		t.Errorf("Unexpected bookmark: %+v", bookmark)
+	}
+	path := filepath.Join(s.Scripts, bookmark.Target)
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(saved) != body {
+		t.Errorf("Expected: %q\nGot: %q", body, saved)
+	}
+	if info, err := os.Stat(path); err != nil || runtime.GOOS != "windows" && info.Mode().Perm()&0100 == 0 {
+		t.Errorf("Expected an executable script\nGot: %v %v", info.Mode(), err)
+	}
+
+	output, err = executeCommand(root, "list")
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if expected := "ID: BOOKMARK: KIND: COMMAND\n1: two lines: script: echo one\n"; output != expected {
+		t.Errorf("Expected: %s\nGot: %s", expected, output)
+	}
+
+	root.SetIn(strings.NewReader("echo no shebang\n"))
+	_, err = executeCommand(root, "add", "--script", "plain")
+	if err == nil || !strings.Contains(err.Error(), "shebang") {
+		t.Errorf("Expected an error for a script without a shebang\nGot: %v", err)
+	}
+	root.SetIn(strings.NewReader("#!/bin/sh\necho 'unterminated\n"))
+	_, err = executeCommand(root, "add", "--script", "broken")
+	if err == nil || !strings.Contains(err.Error(), "invalid script") {
+		t.Errorf("Expected a syntax error\nGot: %v", err)
+	}
+	if _, found := s.Bookmarks["plain"]; found {
+		t.Error("Expected the invalid script not to be added")
+	}
+}
+
+func TestBookmarkShowCmd(t *testing.T) {
+	s := newScriptStore(t)
+	s.Bookmarks["ls"] = store.Bookmark{Command: "ls -al"}
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			"script",
+			[]string{"show", "deploy"},
+			"1 │ #!/bin/sh\n" +
+				"2 │ # Deploy the api\n" +
+				"3 │ for env in \"$@\"; do\n" +
+				"4 │   echo \"deploying $env\" \\\n" +
+				"5 ┆     >> \"$LOG\"\n" +
+				"6 │ done\n",
+		},
+		{
+			"color",
+			[]string{"show", "--color", "always", "deploy"},
+			"\x1b[2m1 │\x1b[0m \x1b[2m#!/bin/sh\x1b[0m\n" +
+				"\x1b[2m2 │\x1b[0m \x1b[2m# Deploy the api\x1b[0m\n" +
+				"\x1b[2m3 │\x1b[0m for env in \"$@\"; do\n" +
+				"\x1b[2m4 │\x1b[0m   echo \"deploying $env\" \\\n" +
+				"\x1b[2m5 ┆\x1b[0m     >> \"$LOG\"\n" +
+				"\x1b[2m6 │\x1b[0m done\n",
+		},
+		{"command", []string{"show", "ls"}, "ls -al\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := cmd.NewRootCmd()
+			root.AddCommand(cmd.BookmarkShowCmd(s))
+			output, err := executeCommand(root, tt.args...)
+			if err != nil {
+				t.Fatalf("Error: %s", err)
+			}
+			if output != tt.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", tt.expected, output)
+			}
+		})
+	}
+}
+
+func TestBookmarkExecCmdScript(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the script is a shell script")
+	}
+	s := newScriptStore(t)
+	log := filepath.Join(t.TempDir(), "log")
+	t.Setenv("LOG", log)
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.BookmarkExecCmd(s, cmd.NewPrompter()))
+	if _, err := executeCommand(root, "exec", "deploy", "staging", "--", "prod"); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	output, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "deploying staging\ndeploying prod\n"; string(output) != expected {
+		t.Errorf("Expected: %q\nGot: %q", expected, output)
+	}
+	if s.Bookmarks["deploy"].RunCount != 1 {
+		t.Errorf("Expected the run to be recorded\nGot: %+v", s.Bookmarks["deploy"])
+	}
+	root = cmd.NewRootCmd()
+	root.AddCommand(cmd.BookmarkExecCmd(s, cmd.NewPrompter()))
+	if _, err := executeCommand(root, "exec", "--shell", "zsh", "deploy"); err == nil {
+		t.Error("Expected an error for --shell")
+	}
+}
+
+func TestScriptFiles(t *testing.T) {
+	s := newScriptStore(t)
+	root := cmd.NewRootCmd()
+	root.AddCommand(
+		cmd.BookmarkAddCmd(s, cmd.NewPrompter()),
+		cmd.BookmarkCopyCmd(s),
+		cmd.BookmarkRemoveCmd(s, cmd.NewPrompter()),
+	)
+	if _, err := executeCommand(root, "copy", "deploy", "deploy2"); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	copied := s.Bookmarks["deploy2"]
+	if copied.Kind != store.KindScript || copied.Target == "deploy" {
+		t.Fatalf("Expected the script to be copied to a new file\nGot: %+v", copied)
+	}
+	original, _ := os.ReadFile(filepath.Join(s.Scripts, "deploy"))
+	duplicate, err := os.ReadFile(filepath.Join(s.Scripts, copied.Target))
+	if err != nil || string(duplicate) != string(original) {
+		t.Errorf("Expected: %q\nGot: %q %v", original, duplicate, err)
+	}
+
+	if _, err := executeCommand(root, "remove", "--yes", "deploy2"); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(s.Scripts, copied.Target)); !os.IsNotExist(err) {
+		t.Errorf("Expected the script of a removed bookmark to be removed\nGot: %v", err)
+	}
+
+	if _, err := executeCommand(root, "add", "--yes", "deploy", "make deploy"); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(s.Scripts, "deploy")); !os.IsNotExist(err) {
+		t.Errorf("Expected the script of a replaced bookmark to be removed\nGot: %v", err)
+	}
+}
+
+func TestScriptFilesFailedModify(t *testing.T) {
+	s := newScriptStore(t)
+	s.ModifyErr = errors.New("write failed")
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.BookmarkAddCmd(s, cmd.NewPrompter()), cmd.BookmarkCopyCmd(s))
+	root.SetIn(strings.NewReader("#!/bin/sh\necho new\n"))
+	if _, err := executeCommand(root, "add", "--script", "new"); err == nil {
+		t.Error("Expected the add to fail")
+	}
+	root.SetIn(strings.NewReader("#!/bin/sh\necho replaced\n"))
+	if _, err := executeCommand(root, "add", "--script", "--yes", "deploy"); err == nil {
+		t.Error("Expected the replacement to fail")
+	}
+	if _, err := executeCommand(root, "copy", "deploy", "deploy2"); err == nil {
+		t.Error("Expected the copy to fail")
+	}
+	entries, err := os.ReadDir(s.Scripts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "deploy" {
+		t.Errorf("Expected only the script of deploy to be left\nGot: %v", entries)
+	}
+	if body, _ := os.ReadFile(filepath.Join(s.Scripts, "deploy")); !strings.Contains(string(body), "Deploy the api") {
+		t.Errorf("Expected the script of deploy to be unchanged\nGot: %s", body)
+	}
+}
+
+func TestScriptFilesFailedCopyForce(t *testing.T) {
+	s := newScriptStore(t)
+	s.Bookmarks["broken"] = store.Bookmark{Kind: store.KindScript, Target: "missing"}
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.BookmarkCopyCmd(s))
+	output, err := executeCommand(root, "copy", "--force", "broken", "deploy")
+	if err == nil {
+		t.Fatal("Expected the copy of a missing script to fail")
+	}
+	if strings.Contains(output, "Usage:") {
+		t.Errorf("Expected no usage for a failed copy\nGot: %s", output)
+	}
+	if s.Bookmarks["deploy"].Target != "deploy" {
+		t.Errorf("Expected the replaced bookmark to be kept\nGot: %+v", s.Bookmarks["deploy"])
+	}
+	if _, err := os.Stat(filepath.Join(s.Scripts, "deploy")); err != nil {
+		t.Errorf("Expected the script of the replaced bookmark to be kept\nGot: %s", err)
+	}
+}
+
+func TestBookmarkEditCmdScript(t *testing.T) {
+	log := fakeEditor(t, `sed 's/Deploy the api/Deploy everything/' "$1" > "$1.new" && mv "$1.new" "$1"`)
+	s := newScriptStore(t)
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.BookmarkEditCmd(s))
+	output, err := executeCommand(root, "edit", "deploy")
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if output != "Bookmark \"deploy\" has been updated successfully!\n" {
+		t.Errorf("Unexpected output: %s", output)
+	}
+	edited, err := os.ReadFile(filepath.Join(s.Scripts, "deploy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(edited), "#!/bin/sh\n# Deploy everything\n") {
+		t.Errorf("Unexpected script: %q", edited)
+	}
+	opened, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(opened), "# Edit the script of the bookmark \"deploy\"") {
+		t.Errorf("Expected the header in the edited file\nGot: %s", opened)
+	}
+}
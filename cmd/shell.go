@@ -0,0 +1,234 @@
+// Copyright (C) 2022 Henrik A. Christensen
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/henrikac/bookmark/internal/placeholder"
+	"github.com/henrikac/bookmark/internal/shellwords"
+	"github.com/spf13/viper"
+)
+
+// noShell is the shell name used for bookmarks that are executed directly
+// without an interpreter.
+const noShell = "none"
+
+// A shell describes how a bookmarked command is run by an interpreter.
+type shell struct {
+	// programs are the names of the interpreter's executable. The first
+	// one found in PATH is used.
+	programs []string
+	// quote quotes placeholder values and extra arguments.
+	quote placeholder.QuoteFunc
+	// line returns the script the interpreter runs for script and the
+	// extra arguments given after "--". The arguments are appended to
+	// script unless the interpreter passes them on separately.
+	line func(script string, extra []string) string
+	// args returns the arguments that make the interpreter run line, as
+	// returned by line, with the extra arguments.
+	args func(line, name string, extra []string) []string
+	// posix reports whether the interpreter understands POSIX shell syntax.
+	posix bool
+}
+
+// fishArgvRe matches references to the arguments of a fish script.
+var fishArgvRe = regexp.MustCompile(`\$argv\b`)
+
+// posixShell returns a shell for a POSIX compatible interpreter.
+func posixShell(program string) shell {
+	return shell{
+		programs: []string{program},
+		quote:    placeholder.ShellQuote,
+		line: func(script string, extra []string) string {
+			return appendArgs(script, extra, placeholder.ShellQuote, positionalParamRe)
+		},
+		args: func(line, name string, extra []string) []string {
+			return append([]string{"-c", line, name}, extra...)
+		},
+		posix: true,
+	}
+}
+
+// shells lists the supported interpreters by name.
+var shells = map[string]shell{
+	"sh":   posixShell("sh"),
+	"bash": posixShell("bash"),
+	"zsh":  posixShell("zsh"),
+	"fish": {
+		programs: []string{"fish"},
+		quote:    fishQuote,
+		line: func(script string, extra []string) string {
+			return appendArgs(script, extra, fishQuote, fishArgvRe)
+		},
+		args: func(line, name string, extra []string) []string {
+			return append([]string{"-c", line}, extra...)
+		},
+	},
+	"pwsh": {
+		programs: []string{"pwsh", "powershell"},
+		quote:    pwshQuote,
+		line: func(script string, extra []string) string {
+			return appendArgs(script, extra, pwshQuote, nil)
+		},
+		args: func(line, name string, extra []string) []string {
+			return []string{"-NoProfile", "-Command", line}
+		},
+	},
+	"python": {
+		programs: []string{"python3", "python"},
+		quote:    pythonQuote,
+		line: func(script string, extra []string) string {
+			return script
+		},
+		args: func(line, name string, extra []string) []string {
+			return append([]string{"-c", line}, extra...)
+		},
+	},
+	"cmd": {
+		programs: []string{"cmd"},
+		quote:    cmdQuote,
+		line: func(script string, extra []string) string {
+			return appendArgs(script, extra, cmdQuote, nil)
+		},
+		args: func(line, name string, extra []string) []string {
+			return []string{"/c", line}
+		},
+	},
+	noShell: {
+		quote: placeholder.ShellQuote,
+		line: func(script string, extra []string) string {
+			return appendArgs(script, extra, placeholder.ShellQuote, nil)
+		},
+		posix: true,
+	},
+}
+
+// shellNames returns the names of the supported interpreters.
+func shellNames() []string {
+	names := make([]string, 0, len(shells))
+	for name := range shells {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateShellName returns an error if name is not a supported interpreter.
+func validateShellName(name string) error {
+	if _, found := shells[name]; !found {
+		return fmt.Errorf(
+			"unknown shell \"%s\", expected one of: %s",
+			name,
+			strings.Join(shellNames(), ", "),
+		)
+	}
+	return nil
+}
+
+// resolveShell returns the name of the interpreter used to run a bookmark.
+// The first non-empty of the given names is used, falling back to the
+// configured default shell and then to the platform default.
+func resolveShell(names ...string) string {
+	for _, name := range names {
+		if name != "" {
+			return name
+		}
+	}
+	if name := viper.GetViper().GetString("defaultShell"); name != "" {
+		return name
+	}
+	if runtime.GOOS == "windows" {
+		return "cmd"
+	}
+	return "bash"
+}
+
+// command returns the command that runs script with the interpreter and
+// the script the interpreter runs, see line. name is the name of the
+// bookmark and extra are the arguments given after "--".
+func (s shell) command(name, script string, extra []string) (*exec.Cmd, string, error) {
+	line := s.line(script, extra)
+	if len(s.programs) == 0 {
+		argv, err := shellwords.Split(script)
+		if err != nil {
+			return nil, "", fmt.Errorf("unable to run bookmark \"%s\" without a shell: %w", name, err)
+		}
+		argv = append(argv, extra...)
+		if len(argv) == 0 {
+			return nil, "", fmt.Errorf("bookmark \"%s\" has no command", name)
+		}
+		// Paths are resolved relative to the bookmark's working directory
+		// when the command is started.
+		if _, err := exec.LookPath(argv[0]); err != nil && filepath.Base(argv[0]) == argv[0] {
+			return nil, "", fmt.Errorf("program \"%s\" for bookmark \"%s\" was not found in PATH", argv[0], name)
+		}
+		return exec.Command(argv[0], argv[1:]...), line, nil
+	}
+	if s.posix && runtime.GOOS != "windows" {
+		if argv, ok := directCommand(line); ok {
+			return exec.Command(argv[0], argv[1:]...), line, nil
+		}
+	}
+	for _, program := range s.programs {
+		if path, err := exec.LookPath(program); err == nil {
+			return exec.Command(path, s.args(line, name, extra)...), line, nil
+		}
+	}
+	return nil, "", fmt.Errorf("shell \"%s\" for bookmark \"%s\" was not found in PATH", s.programs[0], name)
+}
+
+// fishQuote is a placeholder.QuoteFunc for fish.
+func fishQuote(value string, quote byte) string {
+	switch quote {
+	case '\'':
+		return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+	case '"':
+		return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`).Replace(value)
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// pwshQuote is a placeholder.QuoteFunc for PowerShell.
+func pwshQuote(value string, quote byte) string {
+	switch quote {
+	case '\'':
+		return strings.ReplaceAll(value, "'", "''")
+	case '"':
+		return strings.NewReplacer("`", "``", `"`, "`\"", `$`, "`$").Replace(value)
+	}
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// pythonQuote is a placeholder.QuoteFunc for python. Unquoted placeholders
+// are replaced by string literals.
+func pythonQuote(value string, quote byte) string {
+	literal := strconv.Quote(value)
+	switch quote {
+	case '"':
+		return literal[1 : len(literal)-1]
+	case '\'':
+		return strings.ReplaceAll(literal[1:len(literal)-1], "'", `\'`)
+	}
+	return literal
+}
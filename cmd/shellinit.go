@@ -0,0 +1,153 @@
+// Copyright (C) 2022 Henrik A. Christensen
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// shellWidgets are the scripts printed by shell-init. Each one binds
+// ctrl-g to a widget that replaces the command line with the command of a
+// picked bookmark. The current command line is used as the initial query.
+// They also define a bookmark-save function that passes the previous
+// command from the history of the running shell to "bookmark save", and a
+// bcd function that changes to the directory printed by "bookmark jump".
+var shellWidgets = map[string]string{
+	"bash": `# bookmark widget for bash, add this to ~/.bashrc:
+#   eval "$(bookmark shell-init bash)"
+__bookmark_widget() {
+  local selected
+  selected="$(command bookmark pick --print --shell bash -- "$READLINE_LINE")" || return
+  if [[ -n $selected ]]; then
+    READLINE_LINE=$selected
+    READLINE_POINT=${#selected}
+  fi
+}
+bind -m emacs-standard -x '"\C-g": __bookmark_widget'
+bind -m vi-insert -x '"\C-g": __bookmark_widget'
+bind -m vi-command -x '"\C-g": __bookmark_widget'
+
+# bookmark-save <bookmark> [flags] bookmarks the previous command.
+bookmark-save() {
+  HISTTIMEFORMAT= builtin fc -ln -1 | command bookmark save --from-stdin "$@"
+}
+
+# bcd <query> changes to the best matching directory bookmark.
+bcd() {
+  local dir
+  dir="$(command bookmark jump -- "$@")" && builtin cd -- "$dir"
+}
+`,
+	"zsh": `# bookmark widget for zsh, add this to ~/.zshrc:
+#   eval "$(bookmark shell-init zsh)"
+__bookmark_widget() {
+  local selected ret
+  selected="$(command bookmark pick --print --shell zsh -- "$BUFFER" < /dev/tty)"
+  ret=$?
+  if [[ -n $selected ]]; then
+    BUFFER=$selected
+    CURSOR=${#BUFFER}
+  fi
+  zle reset-prompt
+  return $ret
+}
+zle -N __bookmark_widget
+bindkey -M emacs '^G' __bookmark_widget
+bindkey -M viins '^G' __bookmark_widget
+bindkey -M vicmd '^G' __bookmark_widget
+
+# bookmark-save <bookmark> [flags] bookmarks the previous command.
+bookmark-save() {
+  print -r -- "${history[$((HISTCMD - 1))]}" | command bookmark save --from-stdin "$@"
+}
+
+# bcd <query> changes to the best matching directory bookmark.
+bcd() {
+  local dir
+  dir="$(command bookmark jump -- "$@")" && builtin cd -- "$dir"
+}
+`,
+	"fish": `# bookmark widget for fish, add this to ~/.config/fish/config.fish:
+#   bookmark shell-init fish | source
+function __bookmark_widget
+    set -l selected (command bookmark pick --print --shell fish -- (commandline | string collect) | string collect)
+    if test -n "$selected"
+        commandline -r -- $selected
+        commandline -C (string length -- $selected)
+    end
+    commandline -f repaint
+end
+bind \cg __bookmark_widget
+if bind -M insert >/dev/null 2>&1
+    bind -M insert \cg __bookmark_widget
+end
+
+# bookmark-save <bookmark> [flags] bookmarks the previous command.
+function bookmark-save
+    printf '%s\n' $history[1] | command bookmark save --from-stdin $argv
+end
+
+# bcd <query> changes to the best matching directory bookmark.
+function bcd
+    set -l dir (command bookmark jump -- $argv); and builtin cd -- $dir
+end
+`,
+}
+
+var shellInitCmd = NewShellInitCmd()
+
+// NewShellInitCmd initializes a new shell-init command.
+func NewShellInitCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "shell-init <" + strings.Join(widgetShells(), "|") + ">",
+		Short: "Print a widget that inserts a bookmark into the command line",
+		Long: `Print a shell widget bound to ctrl-g.
+
+The widget opens the picker and replaces the command line with the command
+of the chosen bookmark, with its placeholders filled in, so it can be edited
+before it is run. It also defines a bookmark-save function that bookmarks
+the previous command and a bcd function that changes to a directory
+bookmark. Load it from the startup file of your shell:
+
+  bash  eval "$(bookmark shell-init bash)"
+  zsh   eval "$(bookmark shell-init zsh)"
+  fish  bookmark shell-init fish | source`,
+		Args:      cobra.ExactValidArgs(1),
+		ValidArgs: widgetShells(),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, err := io.WriteString(cmd.OutOrStdout(), shellWidgets[args[0]])
+			return err
+		},
+	}
+}
+
+// widgetShells returns the names of the shells shell-init supports.
+func widgetShells() []string {
+	names := make([]string, 0, len(shellWidgets))
+	for name := range shellWidgets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	rootCmd.AddCommand(shellInitCmd)
+}
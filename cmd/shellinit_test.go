@@ -0,0 +1,38 @@
+package cmd_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/henrikac/bookmark/cmd"
+)
+
+func TestShellInitCmd(t *testing.T) {
+	tests := []struct {
+		shell    string
+		expected string
+	}{
+		{"bash", `bind -m emacs-standard -x '"\C-g": __bookmark_widget'`},
+		{"zsh", "bindkey -M emacs '^G' __bookmark_widget"},
+		{"fish", `bind \cg __bookmark_widget`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			root := cmd.NewRootCmd()
+			root.AddCommand(cmd.NewShellInitCmd())
+			output, err := executeCommand(root, "shell-init", tt.shell)
+			if err != nil {
+				t.Errorf("Error: %s", err)
+			}
+			if !strings.Contains(output, "bookmark pick --print --shell "+tt.shell) || !strings.Contains(output, tt.expected) || !strings.Contains(output, "command bookmark jump -- ") {
+				t.Errorf("Unexpected widget:\n%s", output)
+			}
+		})
+	}
+
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.NewShellInitCmd())
+	if _, err := executeCommand(root, "shell-init", "tcsh"); err == nil {
+		t.Error("Expected an error for an unsupported shell")
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright (C) 2022 Henrik A. Christensen
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"github.com/henrikac/bookmark/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var (
+	storeCmd        = NewStoreCmd()
+	storeMigrateCmd = StoreMigrateCmd(bookmarkStore)
+)
+
+// NewStoreCmd initializes a new store command.
+func NewStoreCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "store",
+		Short: "Manage the bookmark store",
+	}
+}
+
+// StoreMigrateCmd initializes a new store migrate command.
+func StoreMigrateCmd(m store.BookmarkStoreMigrator) *cobra.Command {
+	var dryRun bool
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Upgrade the bookmark store to the latest format",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			result, err := m.Migrate(dryRun)
+			if err != nil {
+				return err
+			}
+			if len(result.Steps) == 0 {
+				cmd.Printf("The store is up to date (version %d)\n", result.To)
+				return nil
+			}
+			if dryRun {
+				cmd.Printf("Would migrate the store from version %d to %d:\n", result.From, result.To)
+			} else {
+				cmd.Printf("Migrated the store from version %d to %d:\n", result.From, result.To)
+			}
+			for _, step := range result.Steps {
+				cmd.Printf("  v%d -> v%d: %s\n", step.From, step.To, step.Description)
+				for _, change := range step.Changes {
+					cmd.Printf("    - %s\n", change)
+				}
+			}
+			if result.Backup != "" {
+				cmd.Printf("A backup of the old store was saved to %s\n", result.Backup)
+			}
+			return nil
+		},
+	}
+	migrateCmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the changes without writing them")
+	return migrateCmd
+}
+
+func init() {
+	storeCmd.AddCommand(storeMigrateCmd)
+	rootCmd.AddCommand(storeCmd)
+}
@@ -0,0 +1,195 @@
+// Copyright (C) 2022 Henrik A. Christensen
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/henrikac/bookmark/internal/store"
+	"github.com/henrikac/bookmark/internal/tagexpr"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tagCmd       = NewTagCmd()
+	tagAddCmd    = TagAddCmd(bookmarkStore)
+	tagRemoveCmd = TagRemoveCmd(bookmarkStore)
+	tagsCmd      = TagsCmd(bookmarkStore)
+)
+
+// NewTagCmd initializes a new tag command.
+func NewTagCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "tag",
+		Short: "Handle the tags of a bookmark",
+	}
+}
+
+// TagAddCmd initializes a new tag add command.
+func TagAddCmd(bs store.BookmarkStoreLoadModifier) *cobra.Command {
+	return &cobra.Command{
+		Use:               "add <bookmark> <tag>...",
+		Short:             "Add tags to a bookmark",
+		Args:              cobra.MinimumNArgs(2),
+		ValidArgsFunction: completeTagArgs(bs, false),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, tags := args[0], args[1:]
+			if err := validateTags(tags); err != nil {
+				return err
+			}
+			return modifyTags(cmd, bs, name, func(bookmark *store.Bookmark) {
+				bookmark.Tags = mergeTags(bookmark.Tags, tags)
+			})
+		},
+	}
+}
+
+// TagRemoveCmd initializes a new tag remove command.
+func TagRemoveCmd(bs store.BookmarkStoreLoadModifier) *cobra.Command {
+	return &cobra.Command{
+		Use:               "remove <bookmark> <tag>...",
+		Short:             "Remove tags from a bookmark",
+		Args:              cobra.MinimumNArgs(2),
+		ValidArgsFunction: completeTagArgs(bs, true),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, tags := args[0], args[1:]
+			return modifyTags(cmd, bs, name, func(bookmark *store.Bookmark) {
+				remove := make(map[string]bool, len(tags))
+				for _, t := range tags {
+					remove[t] = true
+				}
+				kept := make([]string, 0, len(bookmark.Tags))
+				for _, t := range bookmark.Tags {
+					if !remove[t] {
+						kept = append(kept, t)
+					}
+				}
+				if len(kept) == 0 {
+					kept = nil
+				}
+				bookmark.Tags = kept
+			})
+		},
+	}
+}
+
+// TagsCmd initializes a new tags command.
+func TagsCmd(bs store.BookmarkStoreLoader) *cobra.Command {
+	return &cobra.Command{
+		Use:   "tags",
+		Short: "List all tags and the number of bookmarks with each tag",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bookmarks, err := bs.Load()
+			if err != nil {
+				return err
+			}
+			counts := make(map[string]int)
+			for _, bookmark := range bookmarks {
+				for _, t := range bookmark.Tags {
+					counts[t] += 1
+				}
+			}
+			tags := make([]string, 0, len(counts))
+			for t := range counts {
+				tags = append(tags, t)
+			}
+			sort.Strings(tags)
+			cmd.Println("TAG: BOOKMARKS")
+			for _, t := range tags {
+				cmd.Printf("%s: %d\n", t, counts[t])
+			}
+			return nil
+		},
+	}
+}
+
+// modifyTags applies fn to the bookmark with the given name and saves it.
+func modifyTags(cmd *cobra.Command, bs store.BookmarkStoreModifier, name string, fn func(*store.Bookmark)) error {
+	found := true
+	err := bs.Modify(func(bookmarks store.BookmarkContainer) error {
+		bookmark, ok := bookmarks[name]
+		if !ok {
+			found = false
+			return nil
+		}
+		fn(&bookmark)
+		bookmarks[name] = bookmark
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		cmd.Printf("Unable to find bookmark: \"%s\"\n", name)
+		return nil
+	}
+	cmd.Printf("Bookmark \"%s\" has been updated successfully!\n", name)
+	return nil
+}
+
+// validateTags returns an error if any of tags is not a valid tag name.
+func validateTags(tags []string) error {
+	for _, t := range tags {
+		if !tagexpr.Valid(t) {
+			return fmt.Errorf("invalid tag: \"%s\"", t)
+		}
+	}
+	return nil
+}
+
+// mergeTags returns the sorted union of tags and add.
+func mergeTags(tags, add []string) []string {
+	seen := make(map[string]bool, len(tags)+len(add))
+	merged := make([]string, 0, len(tags)+len(add))
+	for _, list := range [][]string{tags, add} {
+		for _, t := range list {
+			if !seen[t] {
+				seen[t] = true
+				merged = append(merged, t)
+			}
+		}
+	}
+	sort.Strings(merged)
+	return merged
+}
+
+// filterBookmarks returns the bookmarks that match the tag expression expr.
+// All bookmarks are returned if expr is empty.
+func filterBookmarks(bookmarks store.BookmarkContainer, expr string) (store.BookmarkContainer, error) {
+	if expr == "" {
+		return bookmarks, nil
+	}
+	x, err := tagexpr.Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	filtered := make(store.BookmarkContainer)
+	for name, bookmark := range bookmarks {
+		if x.Match(bookmark.Tags) {
+			filtered[name] = bookmark
+		}
+	}
+	return filtered, nil
+}
+
+func init() {
+	tagCmd.AddCommand(tagAddCmd)
+	tagCmd.AddCommand(tagRemoveCmd)
+	rootCmd.AddCommand(tagCmd)
+	rootCmd.AddCommand(tagsCmd)
+}
@@ -0,0 +1,149 @@
+package cmd_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/henrikac/bookmark/cmd"
+	"github.com/henrikac/bookmark/internal/store"
+)
+
+func newTaggedBookmarkStore() *memoryBookmarkStore {
+	s := newMemoryBookmarkStore()
+	s.Bookmarks["pods"] = store.Bookmark{Command: "kubectl get pods", Tags: []string{"k8s"}}
+	s.Bookmarks["prod-pods"] = store.Bookmark{Command: "kubectl --context prod get pods", Tags: []string{"k8s", "prod"}}
+	s.Bookmarks["ps"] = store.Bookmark{Command: "docker ps", Tags: []string{"docker"}}
+	s.Bookmarks["list"] = store.Bookmark{Command: "ls"}
+	return s
+}
+
+func TestBookmarkAddCmdWithTags(t *testing.T) {
+	s := newMemoryBookmarkStore()
+	root := cmd.NewRootCmd()
+	addCmd := cmd.BookmarkAddCmd(s, cmd.NewPrompter())
+	root.AddCommand(addCmd)
+	_, err := executeCommand(root, "add", "--tag", "prod", "--tag", "k8s", "--tag", "prod", "pods", "kubectl get pods")
+	if err != nil {
+		t.Errorf("Error: %s", err)
+	}
+	expected := []string{"k8s", "prod"}
+	if !reflect.DeepEqual(s.Bookmarks["pods"].Tags, expected) {
+		t.Errorf("Expected: %v\nGot: %v", expected, s.Bookmarks["pods"].Tags)
+	}
+	_, err = executeCommand(root, "add", "--tag", "not valid", "other", "ls")
+	if err == nil {
+		t.Error("Expected an error for an invalid tag")
+	}
+}
+
+func TestTagAddAndRemoveCmd(t *testing.T) {
+	s := newTaggedBookmarkStore()
+	root := cmd.NewRootCmd()
+	tagCmd := cmd.NewTagCmd()
+	tagCmd.AddCommand(cmd.TagAddCmd(s), cmd.TagRemoveCmd(s))
+	root.AddCommand(tagCmd)
+
+	output, err := executeCommand(root, "tag", "add", "ps", "local", "docker")
+	if err != nil {
+		t.Errorf("Error: %s", err)
+	}
+	if output != "Bookmark \"ps\" has been updated successfully!\n" {
+		t.Errorf("Unexpected output: %s", output)
+	}
+	expected := []string{"docker", "local"}
+	if !reflect.DeepEqual(s.Bookmarks["ps"].Tags, expected) {
+		t.Errorf("Expected: %v\nGot: %v", expected, s.Bookmarks["ps"].Tags)
+	}
+
+	original := s.Bookmarks["ps"].Tags
+	_, err = executeCommand(root, "tag", "remove", "ps", "docker")
+	if err != nil {
+		t.Errorf("Error: %s", err)
+	}
+	if !reflect.DeepEqual(s.Bookmarks["ps"].Tags, []string{"local"}) {
+		t.Errorf("Expected: %v\nGot: %v", []string{"local"}, s.Bookmarks["ps"].Tags)
+	}
+	if !reflect.DeepEqual(original, expected) {
+		t.Errorf("Expected the loaded tags to be left unchanged\nGot: %v", original)
+	}
+	_, err = executeCommand(root, "tag", "remove", "ps", "local")
+	if err != nil {
+		t.Errorf("Error: %s", err)
+	}
+	if s.Bookmarks["ps"].Tags != nil {
+		t.Errorf("Expected no tags\nGot: %v", s.Bookmarks["ps"].Tags)
+	}
+
+	output, err = executeCommand(root, "tag", "add", "missing", "x")
+	if err != nil {
+		t.Errorf("Error: %s", err)
+	}
+	if output != "Unable to find bookmark: \"missing\"\n" {
+		t.Errorf("Unexpected output: %s", output)
+	}
+}
+
+func TestTagsCmd(t *testing.T) {
+	s := newTaggedBookmarkStore()
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.TagsCmd(s))
+	output, err := executeCommand(root, "tags")
+	if err != nil {
+		t.Errorf("Error: %s", err)
+	}
+	expected := `TAG: BOOKMARKS
+docker: 1
+k8s: 2
+prod: 1
+`
+	if output != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, output)
+	}
+}
+
+func TestBookmarkListCmdWithTags(t *testing.T) {
+	s := newTaggedBookmarkStore()
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.BookmarkListCmd(s))
+	output, err := executeCommand(root, "list", "--tags", "k8s && !prod || docker")
+	if err != nil {
+		t.Errorf("Error: %s", err)
+	}
+	expected := `ID: BOOKMARK: KIND: COMMAND
+1: pods: command: kubectl get pods
+2: ps: command: docker ps
+`
+	if output != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, output)
+	}
+	_, err = executeCommand(root, "list", "--tags", "k8s &&")
+	if err == nil {
+		t.Error("Expected an error for an invalid tag expression")
+	}
+}
+
+func TestBookmarkSearchCmdWithTags(t *testing.T) {
+	s := newTaggedBookmarkStore()
+	root := cmd.NewRootCmd()
+	root.AddCommand(cmd.BookmarkSearchCmd(s))
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"all matching", []string{"search", "--tags", "k8s"}, "SCORE: BOOKMARK: COMMAND\n0: pods: kubectl get pods\n0: prod-pods: kubectl --context prod get pods\n"},
+		{"matching name", []string{"search", "--tags", "!prod", "--first", "pods"}, "pods\n"},
+		{"filtered name", []string{"search", "--first=false", "--tags", "!k8s", "pods"}, "Unable to find bookmark: \"pods\"\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := executeCommand(root, tt.args...)
+			if err != nil {
+				t.Errorf("Error: %s", err)
+			}
+			if output != tt.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", tt.expected, output)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+// Package clipboard copies text to the system clipboard.
+package clipboard
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ErrUnavailable is returned by Copy if no clipboard program is installed.
+var ErrUnavailable = errors.New("no clipboard program found")
+
+// programs returns the commands that copy their standard input to the
+// clipboard, in order of preference.
+func programs() [][]string {
+	switch runtime.GOOS {
+	case "darwin":
+		return [][]string{{"pbcopy"}}
+	case "windows":
+		return [][]string{{"clip"}}
+	}
+	var cmds [][]string
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		cmds = append(cmds, []string{"wl-copy"})
+	}
+	return append(cmds,
+		[]string{"xclip", "-selection", "clipboard"},
+		[]string{"xsel", "--clipboard", "--input"},
+		[]string{"termux-clipboard-set"},
+	)
+}
+
+// Copy copies text to the clipboard using the first clipboard program
+// that is installed.
+func Copy(text string) error {
+	for _, p := range programs() {
+		path, err := exec.LookPath(p[0])
+		if err != nil {
+			continue
+		}
+		cmd := exec.Command(path, p[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		return cmd.Run()
+	}
+	return ErrUnavailable
+}
+
+// OSC52 returns the escape sequence that asks a terminal to copy text to
+// the clipboard. It works over SSH in terminals that support it.
+func OSC52(text string) string {
+	return "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+}
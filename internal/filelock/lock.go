@@ -0,0 +1,28 @@
+// Package filelock guards files with exclusive advisory locks, so they can
+// be changed by concurrent processes.
+package filelock
+
+import (
+	"os"
+)
+
+// Lock acquires an exclusive lock guarding the file at path. The lock is
+// held on a separate lock file so the file itself can be replaced or
+// renamed while the lock is held. The returned function releases the lock.
+func Lock(path string) (func() error, error) {
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() error {
+		err := unlockFile(f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}, nil
+}
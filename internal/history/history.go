@@ -0,0 +1,196 @@
+// Package history records the executions of bookmarks in an append-only
+// log.
+//
+// The log is a file with one JSON encoded Entry per line. Once the file
+// would grow beyond its maximum size it is rotated: it is renamed with the
+// suffix .1, an existing .1 file becomes .2 and so on, and the oldest file
+// is removed. Writers hold a lock on the log while they rotate and append
+// to it, so concurrent processes neither rotate the log twice nor lose an
+// entry.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/henrikac/bookmark/internal/filelock"
+)
+
+const (
+	// DefaultMaxSize is the default size in bytes at which a log is
+	// rotated.
+	DefaultMaxSize = 1 << 20
+	// DefaultBackups is the default number of rotated files that are kept.
+	DefaultBackups = 3
+)
+
+// An Entry is a single execution of a bookmark.
+type Entry struct {
+	// Time is the time the execution started.
+	Time time.Time `json:"time"`
+	// Name is the name of the bookmark.
+	Name string `json:"name"`
+	// Command is the executed command with its placeholders filled in and
+	// its arguments appended.
+	Command string `json:"command"`
+	// Cwd is the directory the command was run in.
+	Cwd string `json:"cwd"`
+	// DurationMs is how long the command ran in milliseconds.
+	DurationMs int64 `json:"durationMs"`
+	// ExitCode is the exit code of the command. It is -1 if the command
+	// could not be started.
+	ExitCode int `json:"exitCode"`
+	// Error describes why the command failed if it did not exit on its
+	// own, e.g. because it could not be started or timed out.
+	Error string `json:"error,omitempty"`
+}
+
+// Duration returns how long the command ran.
+func (e Entry) Duration() time.Duration {
+	return time.Duration(e.DurationMs) * time.Millisecond
+}
+
+// Failed reports whether the command did not succeed.
+func (e Entry) Failed() bool {
+	return e.ExitCode != 0
+}
+
+// A Log is a history file and its rotated files.
+type Log struct {
+	// Path is the path to the history file.
+	Path string
+	// MaxSize is the size in bytes the history file may grow to before it
+	// is rotated.
+	MaxSize int64
+	// Backups is the number of rotated files that are kept.
+	Backups int
+}
+
+// New returns a log written to path with the default rotation settings.
+func New(path string) *Log {
+	return &Log{Path: path, MaxSize: DefaultMaxSize, Backups: DefaultBackups}
+}
+
+// backup returns the path to the rotated file with the given number.
+func (l *Log) backup(n int) string {
+	return fmt.Sprintf("%s.%d", l.Path, n)
+}
+
+// Append adds e to the end of the log, rotating the log first if e would
+// make it grow beyond its maximum size.
+func (l *Log) Append(e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if err := os.MkdirAll(filepath.Dir(l.Path), 0755); err != nil {
+		return err
+	}
+	unlock, err := filelock.Lock(l.Path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := l.rotate(int64(len(line))); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(line)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// rotate rotates the log if n more bytes would make it grow beyond its
+// maximum size. An empty log is never rotated. The caller must hold the
+// lock on the log.
+func (l *Log) rotate(n int64) error {
+	info, err := os.Stat(l.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 || l.MaxSize <= 0 || info.Size()+n <= l.MaxSize {
+		return nil
+	}
+	if l.Backups <= 0 {
+		return ignoreNotExist(os.Remove(l.Path))
+	}
+	for i := l.Backups; i > 1; i-- {
+		if err := ignoreNotExist(os.Rename(l.backup(i-1), l.backup(i))); err != nil {
+			return err
+		}
+	}
+	return ignoreNotExist(os.Rename(l.Path, l.backup(1)))
+}
+
+// ignoreNotExist returns nil if err reports a missing file, which happens
+// when the log has fewer rotated files than it keeps.
+func ignoreNotExist(err error) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Entries returns the entries of the log and its rotated files, oldest
+// first. Lines that cannot be decoded, e.g. a line cut short by a crash,
+// are skipped.
+func (l *Log) Entries() ([]Entry, error) {
+	var entries []Entry
+	for i := l.Backups; i >= 0; i-- {
+		path := l.Path
+		if i > 0 {
+			path = l.backup(i)
+		}
+		read, err := readEntries(path)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, read...)
+	}
+	return entries, nil
+}
+
+// readEntries returns the entries in the file at path. A missing file has
+// no entries.
+func readEntries(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []Entry
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			var e Entry
+			if json.Unmarshal(line, &e) == nil {
+				entries = append(entries, e)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
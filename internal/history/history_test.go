@@ -0,0 +1,130 @@
+package history_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/henrikac/bookmark/internal/history"
+)
+
+func TestLogAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history", "bookmarks.history")
+	l := history.New(path)
+	start := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	expected := []history.Entry{
+		{Time: start, Name: "deploy", Command: "make deploy", Cwd: "/src", DurationMs: 1500},
+		{Time: start.Add(time.Minute), Name: "test", Command: "go test ./...", Cwd: "/src", DurationMs: 20, ExitCode: 1},
+	}
+	for _, e := range expected {
+		if err := l.Append(e); err != nil {
+			t.Fatalf("Error: %s", err)
+		}
+	}
+	entries, err := l.Entries()
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %d entries\nGot: %+v", len(expected), entries)
+	}
+	for i, e := range entries {
+		if !e.Time.Equal(expected[i].Time) || e.Name != expected[i].Name || e.Command != expected[i].Command || e.ExitCode != expected[i].ExitCode {
+			t.Errorf("Expected: %+v\nGot: %+v", expected[i], e)
+		}
+	}
+	if entries[0].Duration() != 1500*time.Millisecond || entries[0].Failed() || !entries[1].Failed() {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+}
+
+func TestLogRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bookmarks.history")
+	l := &history.Log{Path: path, MaxSize: 200, Backups: 2}
+	for i := 0; i < 20; i++ {
+		if err := l.Append(history.Entry{Name: fmt.Sprintf("b%02d", i), Command: "true"}); err != nil {
+			t.Fatalf("Error: %s", err)
+		}
+	}
+	for _, p := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatalf("Error: %s", err)
+		}
+		if info.Size() > l.MaxSize {
+			t.Errorf("Expected %s to be rotated at %d bytes\nGot: %d bytes", p, l.MaxSize, info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected only %d rotated files\nGot: %v", l.Backups, err)
+	}
+	entries, err := l.Entries()
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if len(entries) == 0 || len(entries) >= 20 {
+		t.Fatalf("Expected the oldest entries to be dropped\nGot: %d entries", len(entries))
+	}
+	for i, e := range entries {
+		if expected := fmt.Sprintf("b%02d", 20-len(entries)+i); e.Name != expected {
+			t.Errorf("Expected: %s\nGot: %s", expected, e.Name)
+		}
+	}
+}
+
+func TestLogConcurrentAppend(t *testing.T) {
+	const writers, perWriter = 8, 50
+	path := filepath.Join(t.TempDir(), "bookmarks.history")
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			// Every writer uses its own log, like separate processes do.
+			l := &history.Log{Path: path, MaxSize: 300, Backups: writers * perWriter}
+			for i := 0; i < perWriter; i++ {
+				if err := l.Append(history.Entry{Name: fmt.Sprintf("w%d-%d", w, i)}); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("Error: %s", err)
+	}
+	entries, err := (&history.Log{Path: path, Backups: writers * perWriter}).Entries()
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		seen[e.Name] = true
+	}
+	if len(entries) != writers*perWriter || len(seen) != writers*perWriter {
+		t.Errorf("Expected %d entries\nGot: %d entries, %d unique", writers*perWriter, len(entries), len(seen))
+	}
+}
+
+func TestLogEntriesSkipsBrokenLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bookmarks.history")
+	content := `{"name":"a","exitCode":0}
+{"name":"b","exi
+{"name":"c","exitCode":2}`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := history.New(path).Entries()
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if len(entries) != 2 || entries[0].Name != "a" || entries[1].Name != "c" || entries[1].ExitCode != 2 {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+}
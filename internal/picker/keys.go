@@ -0,0 +1,122 @@
+package picker
+
+import (
+	"bufio"
+)
+
+// A KeyCode identifies a key that was pressed.
+type KeyCode int
+
+const (
+	// KeyUnknown is an unsupported key or escape sequence.
+	KeyUnknown KeyCode = iota
+	// KeyRune is a printable character, see Key.Rune.
+	KeyRune
+	// KeyCtrl is a letter pressed with the control key, see Key.Rune.
+	KeyCtrl
+	KeyEnter
+	KeyEsc
+	KeyBackspace
+	KeyTab
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyHome
+	KeyEnd
+	KeyPageUp
+	KeyPageDown
+	KeyDelete
+)
+
+// A Key is a key press read from a terminal.
+type Key struct {
+	Code KeyCode
+	// Rune is the character of a KeyRune and the lower case letter of a
+	// KeyCtrl.
+	Rune rune
+}
+
+// csiKeys maps the final byte of a CSI or SS3 escape sequence to a key.
+var csiKeys = map[byte]KeyCode{
+	'A': KeyUp,
+	'B': KeyDown,
+	'C': KeyRight,
+	'D': KeyLeft,
+	'H': KeyHome,
+	'F': KeyEnd,
+}
+
+// tildeKeys maps the parameter of a CSI escape sequence ending in ~ to a
+// key.
+var tildeKeys = map[string]KeyCode{
+	"1": KeyHome,
+	"7": KeyHome,
+	"4": KeyEnd,
+	"8": KeyEnd,
+	"3": KeyDelete,
+	"5": KeyPageUp,
+	"6": KeyPageDown,
+}
+
+// ReadKey reads a single key press from a terminal in raw mode.
+//
+// An escape character is reported as KeyEsc unless it is immediately
+// followed by more input, in which case it starts an escape sequence.
+func ReadKey(r *bufio.Reader) (Key, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return Key{}, err
+	}
+	switch {
+	case b == '\r' || b == '\n':
+		return Key{Code: KeyEnter}, nil
+	case b == 0x7f || b == 0x08:
+		return Key{Code: KeyBackspace}, nil
+	case b == '\t':
+		return Key{Code: KeyTab}, nil
+	case b == 0x1b:
+		if r.Buffered() == 0 {
+			return Key{Code: KeyEsc}, nil
+		}
+		return readEscape(r)
+	case b < 0x20:
+		return Key{Code: KeyCtrl, Rune: rune(b) + 'a' - 1}, nil
+	}
+	if err := r.UnreadByte(); err != nil {
+		return Key{}, err
+	}
+	c, _, err := r.ReadRune()
+	if err != nil {
+		return Key{}, err
+	}
+	return Key{Code: KeyRune, Rune: c}, nil
+}
+
+// readEscape reads the rest of an escape sequence after the escape
+// character. Modifiers such as shift or ctrl pressed with an arrow key are
+// ignored.
+func readEscape(r *bufio.Reader) (Key, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return Key{}, err
+	}
+	if b != '[' && b != 'O' {
+		// Alt combined with another key.
+		return Key{Code: KeyUnknown}, nil
+	}
+	var params []byte
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return Key{}, err
+		}
+		if c >= 0x40 && c <= 0x7e {
+			if c == '~' {
+				return Key{Code: tildeKeys[string(params)]}, nil
+			}
+			return Key{Code: csiKeys[c]}, nil
+		}
+		params = append(params, c)
+	}
+}
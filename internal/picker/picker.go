@@ -0,0 +1,290 @@
+// Package picker implements an interactive terminal UI for choosing a
+// bookmark.
+//
+// The picker filters the bookmarks fuzzily as the user types, shows a
+// preview of the selected bookmark and lets the user run, edit, copy or
+// delete it.
+package picker
+
+import (
+	"sort"
+
+	"github.com/henrikac/bookmark/internal/search"
+	"github.com/henrikac/bookmark/internal/store"
+)
+
+// An Action is what the user asked the picker to do.
+type Action int
+
+const (
+	// ActionNone means that the picker keeps running.
+	ActionNone Action = iota
+	// ActionRun runs the selected bookmark and closes the picker.
+	ActionRun
+	// ActionCancel closes the picker without choosing a bookmark.
+	ActionCancel
+	// ActionEdit saves the edited command of the selected bookmark.
+	ActionEdit
+	// ActionCopy copies the command of the selected bookmark to the
+	// clipboard.
+	ActionCopy
+	// ActionDelete deletes the selected bookmark.
+	ActionDelete
+)
+
+// mode is the state of the input line of the picker.
+type mode int
+
+const (
+	// modeFilter edits the query.
+	modeFilter mode = iota
+	// modeEdit edits the command of the selected bookmark.
+	modeEdit
+	// modeConfirmDelete asks whether the selected bookmark should be
+	// deleted.
+	modeConfirmDelete
+)
+
+// A Model holds the state of the picker. It is updated by key presses and
+// rendered by View, without doing any terminal I/O itself.
+type Model struct {
+	bookmarks store.BookmarkContainer
+	results   []search.Result
+	// query is the filter query and pos is the cursor position in it.
+	query []rune
+	pos   int
+	// input is the command being edited and inputPos is the cursor
+	// position in it.
+	input    []rune
+	inputPos int
+	mode     mode
+	// selected is the index of the selected result and offset is the
+	// index of the first visible result.
+	selected int
+	offset   int
+	status   string
+}
+
+// NewModel returns a model that picks one of bookmarks, filtered by the
+// initial query.
+func NewModel(bookmarks store.BookmarkContainer, query string) *Model {
+	m := &Model{query: []rune(query), pos: len([]rune(query))}
+	m.SetBookmarks(bookmarks)
+	return m
+}
+
+// SetBookmarks replaces the bookmarks of the picker, e.g. after a bookmark
+// was edited. The same bookmark stays selected if it still exists.
+func (m *Model) SetBookmarks(bookmarks store.BookmarkContainer) {
+	m.bookmarks = bookmarks
+	m.filter()
+}
+
+// Query returns the current filter query.
+func (m *Model) Query() string {
+	return string(m.query)
+}
+
+// Input returns the edited command.
+func (m *Model) Input() string {
+	return string(m.input)
+}
+
+// Results returns the bookmarks that match the query, best match first.
+func (m *Model) Results() []search.Result {
+	return m.results
+}
+
+// Selected returns the selected result and false if no bookmark matches
+// the query.
+func (m *Model) Selected() (search.Result, bool) {
+	if len(m.results) == 0 {
+		return search.Result{}, false
+	}
+	return m.results[m.selected], true
+}
+
+// SetStatus sets the message shown below the input line until the next
+// key press.
+func (m *Model) SetStatus(status string) {
+	m.status = status
+}
+
+// filter matches the bookmarks against the query and keeps the selected
+// bookmark selected if it still matches.
+func (m *Model) filter() {
+	prev, hadPrev := m.Selected()
+	var matcher search.Matcher
+	if len(m.query) > 0 {
+		matcher, _ = search.NewMatcher(string(m.query), search.Fuzzy)
+	}
+	m.results = search.Search(m.bookmarks, matcher)
+	if matcher == nil {
+		// Without a query the most used bookmarks are listed first.
+		sort.SliceStable(m.results, func(i, j int) bool {
+			return m.results[i].Bookmark.RunCount > m.results[j].Bookmark.RunCount
+		})
+	}
+	m.selected = 0
+	if hadPrev {
+		for i, r := range m.results {
+			if r.Name == prev.Name {
+				m.selected = i
+				break
+			}
+		}
+	}
+}
+
+// move moves the selection by delta results.
+func (m *Model) move(delta int) {
+	m.selected += delta
+	if m.selected >= len(m.results) {
+		m.selected = len(m.results) - 1
+	}
+	if m.selected < 0 {
+		m.selected = 0
+	}
+}
+
+// pageSize is the number of results moved by page up and page down.
+const pageSize = 10
+
+// Update updates the model with a key press and returns the action the
+// user asked for.
+func (m *Model) Update(k Key) Action {
+	m.status = ""
+	switch m.mode {
+	case modeEdit:
+		return m.updateEdit(k)
+	case modeConfirmDelete:
+		m.mode = modeFilter
+		if k.Code == KeyRune && (k.Rune == 'y' || k.Rune == 'Y') {
+			return ActionDelete
+		}
+		return ActionNone
+	}
+	switch k.Code {
+	case KeyEnter:
+		if _, ok := m.Selected(); ok {
+			return ActionRun
+		}
+	case KeyEsc:
+		return ActionCancel
+	case KeyUp:
+		m.move(-1)
+	case KeyDown, KeyTab:
+		m.move(1)
+	case KeyPageUp:
+		m.move(-pageSize)
+	case KeyPageDown:
+		m.move(pageSize)
+	case KeyCtrl:
+		switch k.Rune {
+		case 'c', 'g', 'q':
+			return ActionCancel
+		case 'p', 'k':
+			m.move(-1)
+		case 'n', 'j':
+			m.move(1)
+		case 'e':
+			if r, ok := m.Selected(); ok {
+				m.mode = modeEdit
+				m.input = []rune(r.Bookmark.Summary())
+				m.inputPos = len(m.input)
+			}
+		case 'y':
+			if _, ok := m.Selected(); ok {
+				return ActionCopy
+			}
+		case 'x':
+			if _, ok := m.Selected(); ok {
+				m.mode = modeConfirmDelete
+			}
+		default:
+			if editLine(&m.query, &m.pos, k) {
+				m.filter()
+			}
+		}
+	default:
+		if editLine(&m.query, &m.pos, k) {
+			m.filter()
+		}
+	}
+	return ActionNone
+}
+
+// updateEdit handles a key press while a command is being edited.
+func (m *Model) updateEdit(k Key) Action {
+	switch {
+	case k.Code == KeyEnter:
+		m.mode = modeFilter
+		return ActionEdit
+	case k.Code == KeyEsc, k.Code == KeyCtrl && (k.Rune == 'c' || k.Rune == 'g'):
+		m.mode = modeFilter
+	default:
+		editLine(&m.input, &m.inputPos, k)
+	}
+	return ActionNone
+}
+
+// editLine applies a line editing key to line with the cursor at pos and
+// reports whether line was changed.
+func editLine(line *[]rune, pos *int, k Key) bool {
+	l, p := *line, *pos
+	switch {
+	case k.Code == KeyRune:
+		l = append(l[:p], append([]rune{k.Rune}, l[p:]...)...)
+		p++
+	case k.Code == KeyBackspace || k.Code == KeyCtrl && k.Rune == 'h':
+		if p == 0 {
+			return false
+		}
+		l = append(l[:p-1], l[p:]...)
+		p--
+	case k.Code == KeyDelete || k.Code == KeyCtrl && k.Rune == 'd':
+		if p == len(l) {
+			return false
+		}
+		l = append(l[:p], l[p+1:]...)
+	case k.Code == KeyLeft || k.Code == KeyCtrl && k.Rune == 'b':
+		if p > 0 {
+			*pos = p - 1
+		}
+		return false
+	case k.Code == KeyRight || k.Code == KeyCtrl && k.Rune == 'f':
+		if p < len(l) {
+			*pos = p + 1
+		}
+		return false
+	case k.Code == KeyHome || k.Code == KeyCtrl && k.Rune == 'a':
+		*pos = 0
+		return false
+	case k.Code == KeyEnd:
+		*pos = len(l)
+		return false
+	case k.Code == KeyCtrl && k.Rune == 'u':
+		if p == 0 {
+			return false
+		}
+		l = l[p:]
+		p = 0
+	case k.Code == KeyCtrl && k.Rune == 'w':
+		start := p
+		for start > 0 && l[start-1] == ' ' {
+			start--
+		}
+		for start > 0 && l[start-1] != ' ' {
+			start--
+		}
+		if start == p {
+			return false
+		}
+		l = append(l[:start], l[p:]...)
+		p = start
+	default:
+		return false
+	}
+	*line, *pos = l, p
+	return true
+}
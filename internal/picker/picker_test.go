@@ -0,0 +1,168 @@
+package picker_test
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/henrikac/bookmark/internal/picker"
+	"github.com/henrikac/bookmark/internal/store"
+)
+
+func TestReadKey(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []picker.Key
+	}{
+		{"ab", []picker.Key{{Code: picker.KeyRune, Rune: 'a'}, {Code: picker.KeyRune, Rune: 'b'}}},
+		{"æ", []picker.Key{{Code: picker.KeyRune, Rune: 'æ'}}},
+		{"\r\x7f\t", []picker.Key{{Code: picker.KeyEnter}, {Code: picker.KeyBackspace}, {Code: picker.KeyTab}}},
+		{"\x03\x10", []picker.Key{{Code: picker.KeyCtrl, Rune: 'c'}, {Code: picker.KeyCtrl, Rune: 'p'}}},
+		{"\x1b[A\x1b[B\x1bOC\x1b[D", []picker.Key{{Code: picker.KeyUp}, {Code: picker.KeyDown}, {Code: picker.KeyRight}, {Code: picker.KeyLeft}}},
+		{"\x1b[5~\x1b[6~\x1b[3~\x1b[1~\x1b[F", []picker.Key{{Code: picker.KeyPageUp}, {Code: picker.KeyPageDown}, {Code: picker.KeyDelete}, {Code: picker.KeyHome}, {Code: picker.KeyEnd}}},
+		{"\x1b[1;5A\x1b[2~", []picker.Key{{Code: picker.KeyUp}, {Code: picker.KeyUnknown}}},
+		{"\x1b", []picker.Key{{Code: picker.KeyEsc}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(tt.input))
+			for i, expected := range tt.expected {
+				k, err := picker.ReadKey(r)
+				if err != nil {
+					t.Fatalf("Error: %s", err)
+				}
+				if k != expected {
+					t.Errorf("Key %d: expected %+v\nGot: %+v", i, expected, k)
+				}
+			}
+			if _, err := picker.ReadKey(r); err == nil {
+				t.Error("Expected the input to be consumed")
+			}
+		})
+	}
+}
+
+func newModel(query string) *picker.Model {
+	return picker.NewModel(store.BookmarkContainer{
+		"pods":   {Command: "kubectl get pods", Tags: []string{"k8s"}, RunCount: 1},
+		"deploy": {Command: "make deploy", Description: "deploy the app", RunCount: 5},
+		"list":   {Command: "ls -al"},
+	}, query)
+}
+
+// typeKeys sends every key to m and returns the last action.
+func typeKeys(m *picker.Model, keys ...picker.Key) picker.Action {
+	var action picker.Action
+	for _, k := range keys {
+		action = m.Update(k)
+	}
+	return action
+}
+
+func runes(s string) []picker.Key {
+	var keys []picker.Key
+	for _, r := range s {
+		keys = append(keys, picker.Key{Code: picker.KeyRune, Rune: r})
+	}
+	return keys
+}
+
+func selected(m *picker.Model) string {
+	r, _ := m.Selected()
+	return r.Name
+}
+
+func TestModelFilter(t *testing.T) {
+	m := newModel("")
+	if got := selected(m); got != "deploy" {
+		t.Errorf("Expected the most used bookmark to be selected\nGot: %s", got)
+	}
+	typeKeys(m, runes("kgp")...)
+	if len(m.Results()) != 1 || selected(m) != "pods" {
+		t.Errorf("Expected only pods to match\nGot: %+v", m.Results())
+	}
+	typeKeys(m, picker.Key{Code: picker.KeyCtrl, Rune: 'u'})
+	if m.Query() != "" || len(m.Results()) != 3 {
+		t.Errorf("Expected the query to be cleared\nGot: %q", m.Query())
+	}
+	if got := selected(m); got != "pods" {
+		t.Errorf("Expected the selection to be kept\nGot: %s", got)
+	}
+	typeKeys(m, runes("xyz")...)
+	if _, ok := m.Selected(); ok {
+		t.Error("Expected nothing to be selected")
+	}
+	if action := m.Update(picker.Key{Code: picker.KeyEnter}); action != picker.ActionNone {
+		t.Errorf("Expected enter to do nothing without a match\nGot: %d", action)
+	}
+}
+
+func TestModelActions(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     []picker.Key
+		action   picker.Action
+		selected string
+	}{
+		{"run", []picker.Key{{Code: picker.KeyEnter}}, picker.ActionRun, "deploy"},
+		{"move", []picker.Key{{Code: picker.KeyDown}, {Code: picker.KeyDown}, {Code: picker.KeyDown}, {Code: picker.KeyUp}, {Code: picker.KeyEnter}}, picker.ActionRun, "pods"},
+		{"page", []picker.Key{{Code: picker.KeyPageDown}, {Code: picker.KeyEnter}}, picker.ActionRun, "list"},
+		{"cancel", []picker.Key{{Code: picker.KeyEsc}}, picker.ActionCancel, "deploy"},
+		{"ctrl-c", []picker.Key{{Code: picker.KeyCtrl, Rune: 'c'}}, picker.ActionCancel, "deploy"},
+		{"copy", []picker.Key{{Code: picker.KeyCtrl, Rune: 'n'}, {Code: picker.KeyCtrl, Rune: 'y'}}, picker.ActionCopy, "pods"},
+		{"delete", []picker.Key{{Code: picker.KeyCtrl, Rune: 'x'}, {Code: picker.KeyRune, Rune: 'y'}}, picker.ActionDelete, "deploy"},
+		{"keep", []picker.Key{{Code: picker.KeyCtrl, Rune: 'x'}, {Code: picker.KeyRune, Rune: 'n'}}, picker.ActionNone, "deploy"},
+		{"cancel edit", []picker.Key{{Code: picker.KeyCtrl, Rune: 'e'}, {Code: picker.KeyEsc}}, picker.ActionNone, "deploy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newModel("")
+			if action := typeKeys(m, tt.keys...); action != tt.action {
+				t.Errorf("Expected action: %d\nGot: %d", tt.action, action)
+			}
+			if got := selected(m); got != tt.selected {
+				t.Errorf("Expected selected: %s\nGot: %s", tt.selected, got)
+			}
+		})
+	}
+}
+
+func TestModelEdit(t *testing.T) {
+	m := newModel("")
+	keys := []picker.Key{
+		{Code: picker.KeyCtrl, Rune: 'e'},
+		{Code: picker.KeyCtrl, Rune: 'w'},
+		{Code: picker.KeyHome},
+		{Code: picker.KeyDelete},
+		{Code: picker.KeyDelete},
+		{Code: picker.KeyDelete},
+		{Code: picker.KeyDelete},
+	}
+	keys = append(keys, runes("task")...)
+	keys = append(keys, picker.Key{Code: picker.KeyEnd})
+	keys = append(keys, runes("release")...)
+	keys = append(keys, picker.Key{Code: picker.KeyEnter})
+	if action := typeKeys(m, keys...); action != picker.ActionEdit {
+		t.Errorf("Expected action: %d\nGot: %d", picker.ActionEdit, action)
+	}
+	if m.Input() != "task release" {
+		t.Errorf("Expected: task release\nGot: %s", m.Input())
+	}
+}
+
+func TestModelView(t *testing.T) {
+	m := newModel("dep")
+	f := m.View(40, 12)
+	if len(f.Lines) != 12 {
+		t.Errorf("Expected 12 lines\nGot: %d", len(f.Lines))
+	}
+	if !strings.HasSuffix(f.Lines[0], "dep") || f.Col != 5 {
+		t.Errorf("Expected the query on the first line\nGot: %q at column %d", f.Lines[0], f.Col)
+	}
+	view := strings.Join(f.Lines, "\n")
+	for _, expected := range []string{"1/3", "\x1b[1;31mdep\x1b[0m\x1b[7mloy", "deploy the app", "enter run"} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("Expected the view to contain %q\nGot:\n%s", expected, view)
+		}
+	}
+}
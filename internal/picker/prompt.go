@@ -0,0 +1,50 @@
+package picker
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Prompt asks for a line of input on t, e.g. the value of a placeholder.
+// The input starts out as value and is edited with the same keys as the
+// query of the picker. The hint is shown below the input line. Prompt
+// returns false if the user cancelled.
+func Prompt(t *TTY, prompt, value, hint string) (string, bool, error) {
+	if _, err := io.WriteString(t, enterAltScreen); err != nil {
+		return "", false, err
+	}
+	defer io.WriteString(t, exitAltScreen)
+	line := []rune(value)
+	pos := len(line)
+	r := bufio.NewReader(t)
+	for {
+		width, _, err := t.Size()
+		if err != nil || width == 0 {
+			width = 80
+		}
+		input, col := scrollLine(line, pos, width-utf8.RuneCountInString(prompt)-1)
+		var b strings.Builder
+		b.WriteString("\x1b[H")
+		b.WriteString(styleBold + prompt + styleReset + input + "\x1b[K\r\n")
+		b.WriteString(styleDim + truncate(hint, width) + styleReset + "\x1b[J")
+		fmt.Fprintf(&b, "\x1b[1;%dH", utf8.RuneCountInString(prompt)+col+1)
+		if _, err := io.WriteString(t, b.String()); err != nil {
+			return "", false, err
+		}
+		k, err := ReadKey(r)
+		if err != nil {
+			return "", false, err
+		}
+		switch {
+		case k.Code == KeyEnter:
+			return string(line), true, nil
+		case k.Code == KeyEsc, k.Code == KeyCtrl && (k.Rune == 'c' || k.Rune == 'g'):
+			return "", false, nil
+		default:
+			editLine(&line, &pos, k)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package picker
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/henrikac/bookmark/internal/clipboard"
+	"github.com/henrikac/bookmark/internal/store"
+)
+
+// A Handler carries out the actions of the picker that change bookmarks.
+type Handler interface {
+	// Update sets the command of the bookmark with the given name, or its
+	// target if it is not a command.
+	Update(name, command string) error
+	// Delete deletes the bookmark with the given name.
+	Delete(name string) error
+}
+
+// Escape sequences that switch to and from the alternate screen of the
+// terminal, so the picker does not overwrite the user's scrollback.
+const (
+	enterAltScreen = "\x1b[?1049h"
+	exitAltScreen  = "\x1b[?1049l"
+)
+
+// Run shows the picker on t, starting with the given query, until the user
+// chooses a bookmark to run or quits. The bookmarks are loaded from bs and
+// reloaded after they were changed by h. Run returns the name of the chosen
+// bookmark, or false if the user quit.
+func Run(t *TTY, bs store.BookmarkStoreLoader, h Handler, query string) (string, bool, error) {
+	bookmarks, err := bs.Load()
+	if err != nil {
+		return "", false, err
+	}
+	m := NewModel(bookmarks, query)
+	if _, err := io.WriteString(t, enterAltScreen); err != nil {
+		return "", false, err
+	}
+	defer io.WriteString(t, exitAltScreen)
+	r := bufio.NewReader(t)
+	for {
+		if err := draw(t, m); err != nil {
+			return "", false, err
+		}
+		k, err := ReadKey(r)
+		if err != nil {
+			return "", false, err
+		}
+		action := m.Update(k)
+		selected, _ := m.Selected()
+		switch action {
+		case ActionRun:
+			return selected.Name, true, nil
+		case ActionCancel:
+			return "", false, nil
+		case ActionCopy:
+			err := clipboard.Copy(selected.Bookmark.Summary())
+			if errors.Is(err, clipboard.ErrUnavailable) {
+				_, err = io.WriteString(t, clipboard.OSC52(selected.Bookmark.Summary()))
+			}
+			if err != nil {
+				m.SetStatus(fmt.Sprintf("Unable to copy: %s", err))
+			} else {
+				m.SetStatus(fmt.Sprintf("Copied the %s of \"%s\"", selected.Bookmark.Kind, selected.Name))
+			}
+			continue
+		case ActionEdit:
+			err = h.Update(selected.Name, m.Input())
+		case ActionDelete:
+			err = h.Delete(selected.Name)
+		default:
+			continue
+		}
+		if err != nil {
+			m.SetStatus(err.Error())
+			continue
+		}
+		bookmarks, err := bs.Load()
+		if err != nil {
+			return "", false, err
+		}
+		m.SetBookmarks(bookmarks)
+		if action == ActionEdit {
+			m.SetStatus(fmt.Sprintf("Updated \"%s\"", selected.Name))
+		} else {
+			m.SetStatus(fmt.Sprintf("Deleted \"%s\"", selected.Name))
+		}
+	}
+}
+
+// draw renders m on the whole terminal.
+func draw(t *TTY, m *Model) error {
+	width, height, err := t.Size()
+	if err != nil || width == 0 {
+		// The size of e.g. a serial console is unknown.
+		width, height = 80, 24
+	}
+	f := m.View(width, height)
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	for i, line := range f.Lines {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(line)
+		b.WriteString("\x1b[K")
+	}
+	b.WriteString("\x1b[J")
+	fmt.Fprintf(&b, "\x1b[%d;%dH", f.Row+1, f.Col+1)
+	_, err = io.WriteString(t, b.String())
+	return err
+}
@@ -0,0 +1,74 @@
+package picker
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// ErrNoTerminal is returned by Open if there is no terminal to show the
+// picker on.
+var ErrNoTerminal = errors.New("no terminal available")
+
+// A TTY is the terminal the picker is shown on.
+type TTY struct {
+	in, out *os.File
+	state   *term.State
+	// restoreConsole undoes platform specific console changes.
+	restoreConsole func()
+}
+
+// Open opens the controlling terminal of the process and puts it in raw
+// mode. The picker is shown on the terminal even if the standard input or
+// output is redirected.
+func Open() (*TTY, error) {
+	t, err := openTTY()
+	if err != nil {
+		return nil, err
+	}
+	if !term.IsTerminal(int(t.in.Fd())) || !term.IsTerminal(int(t.out.Fd())) {
+		t.close()
+		return nil, ErrNoTerminal
+	}
+	t.state, err = term.MakeRaw(int(t.in.Fd()))
+	if err != nil {
+		t.close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// Size returns the width and height of the terminal.
+func (t *TTY) Size() (width, height int, err error) {
+	return term.GetSize(int(t.out.Fd()))
+}
+
+// Write writes p to the terminal.
+func (t *TTY) Write(p []byte) (int, error) {
+	return t.out.Write(p)
+}
+
+// Read reads input from the terminal.
+func (t *TTY) Read(p []byte) (int, error) {
+	return t.in.Read(p)
+}
+
+// Close restores the terminal to the state it was in before Open and
+// closes it.
+func (t *TTY) Close() error {
+	err := term.Restore(int(t.in.Fd()), t.state)
+	t.close()
+	return err
+}
+
+// close closes the files of the terminal.
+func (t *TTY) close() {
+	if t.restoreConsole != nil {
+		t.restoreConsole()
+	}
+	t.in.Close()
+	if t.out != t.in {
+		t.out.Close()
+	}
+}
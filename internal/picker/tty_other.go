@@ -0,0 +1,8 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+
+package picker
+
+// openTTY always fails on platforms without terminal support.
+func openTTY() (*TTY, error) {
+	return nil, ErrNoTerminal
+}
@@ -0,0 +1,14 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package picker
+
+import "os"
+
+// openTTY opens the controlling terminal.
+func openTTY() (*TTY, error) {
+	f, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, ErrNoTerminal
+	}
+	return &TTY{in: f, out: f}, nil
+}
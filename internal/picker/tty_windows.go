@@ -0,0 +1,34 @@
+//go:build windows
+
+package picker
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// openTTY opens the console and enables the processing of the escape
+// sequences the picker is drawn with.
+func openTTY() (*TTY, error) {
+	in, err := os.OpenFile("CONIN$", os.O_RDWR, 0)
+	if err != nil {
+		return nil, ErrNoTerminal
+	}
+	out, err := os.OpenFile("CONOUT$", os.O_RDWR, 0)
+	if err != nil {
+		in.Close()
+		return nil, ErrNoTerminal
+	}
+	t := &TTY{in: in, out: out}
+	h := windows.Handle(out.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(h, &mode); err == nil {
+		if windows.SetConsoleMode(h, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING) == nil {
+			t.restoreConsole = func() {
+				_ = windows.SetConsoleMode(h, mode)
+			}
+		}
+	}
+	return t, nil
+}
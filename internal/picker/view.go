@@ -0,0 +1,231 @@
+package picker
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/henrikac/bookmark/internal/search"
+)
+
+// ANSI escape sequences used to style the picker.
+const (
+	styleReset     = "\x1b[0m"
+	styleSelected  = "\x1b[7m"
+	styleHighlight = "\x1b[1;31m"
+	styleDim       = "\x1b[2m"
+	styleBold      = "\x1b[1m"
+)
+
+// helpLine lists the key bindings of the picker.
+const helpLine = "enter run · ctrl-e edit · ctrl-y copy · ctrl-x delete · esc quit"
+
+// A Frame is a rendered picker.
+type Frame struct {
+	// Lines are the lines of the screen, which may contain ANSI escape
+	// sequences.
+	Lines []string
+	// Row and Col are the zero-based position of the cursor.
+	Row, Col int
+}
+
+// View renders the model on a screen of the given size.
+//
+// The input line is at the top, followed by the matching bookmarks, a
+// preview of the selected bookmark and a line describing the key bindings.
+func (m *Model) View(width, height int) Frame {
+	if width < 10 {
+		width = 10
+	}
+	if height < 8 {
+		height = 8
+	}
+	var f Frame
+	var prompt string
+	var line []rune
+	var pos int
+	switch m.mode {
+	case modeEdit:
+		prompt, line, pos = "edit> ", m.input, m.inputPos
+	case modeConfirmDelete:
+		r, _ := m.Selected()
+		prompt = fmt.Sprintf("Delete \"%s\" (y/N)? ", r.Name)
+	default:
+		prompt, line, pos = "> ", m.query, m.pos
+	}
+	input, col := scrollLine(line, pos, width-utf8.RuneCountInString(prompt)-1)
+	f.Lines = append(f.Lines, styleBold+prompt+styleReset+input)
+	f.Col = utf8.RuneCountInString(prompt) + col
+
+	info := fmt.Sprintf("  %d/%d", len(m.results), len(m.bookmarks))
+	if m.status != "" {
+		info += "  " + m.status
+	}
+	f.Lines = append(f.Lines, styleDim+truncate(info, width)+styleReset)
+
+	preview := m.preview(width)
+	listHeight := height - len(preview) - 4
+	if listHeight < 1 {
+		listHeight = 1
+		preview = preview[:height-5]
+	}
+	if m.selected < m.offset {
+		m.offset = m.selected
+	}
+	if m.selected >= m.offset+listHeight {
+		m.offset = m.selected - listHeight + 1
+	}
+	nameWidth := 0
+	for _, r := range m.results {
+		if n := utf8.RuneCountInString(r.Name); n > nameWidth {
+			nameWidth = n
+		}
+	}
+	if nameWidth > width/3 {
+		nameWidth = width / 3
+	}
+	for i := m.offset; i < m.offset+listHeight; i++ {
+		if i >= len(m.results) {
+			f.Lines = append(f.Lines, "")
+			continue
+		}
+		r := m.results[i]
+		name := renderField(r, "name", r.Name, nameWidth)
+		name += strings.Repeat(" ", nameWidth-visibleWidth(r.Name, nameWidth))
+		command := renderField(r, search.SummaryField(r.Bookmark), r.Bookmark.Summary(), width-nameWidth-4)
+		if i == m.selected {
+			// Restore the selection style after every highlighted span.
+			text := "> " + name + "  " + command
+			text = strings.ReplaceAll(text, styleReset, styleReset+styleSelected)
+			f.Lines = append(f.Lines, styleSelected+text+strings.Repeat(" ", padding(r, width, nameWidth))+styleReset)
+		} else {
+			f.Lines = append(f.Lines, "  "+name+"  "+command)
+		}
+	}
+	f.Lines = append(f.Lines, styleDim+strings.Repeat("─", width)+styleReset)
+	f.Lines = append(f.Lines, preview...)
+	f.Lines = append(f.Lines, styleDim+truncate(helpLine, width)+styleReset)
+	return f
+}
+
+// preview renders the details of the selected bookmark.
+func (m *Model) preview(width int) []string {
+	r, ok := m.Selected()
+	if !ok {
+		return []string{"No bookmarks match the query"}
+	}
+	var lines []string
+	add := func(label, value string) {
+		if value == "" {
+			return
+		}
+		for i, l := range wrap(value, width-len(label)-2) {
+			if i == 0 {
+				lines = append(lines, styleBold+label+":"+styleReset+" "+l)
+			} else {
+				lines = append(lines, strings.Repeat(" ", len(label)+2)+l)
+			}
+		}
+	}
+	b := r.Bookmark
+	add(b.Kind.String(), b.Summary())
+	add("description", b.Description)
+	add("tags", strings.Join(b.Tags, ", "))
+	add("shell", b.Shell)
+	add("cwd", b.Cwd)
+	if len(lines) > 12 {
+		lines = lines[:12]
+	}
+	return lines
+}
+
+// renderField truncates value to width and highlights the spans of the
+// field that matched the query.
+func renderField(r search.Result, field, value string, width int) string {
+	truncated := truncate(value, width)
+	f, ok := r.Field(field)
+	if !ok {
+		return truncated
+	}
+	// Spans are only highlighted in the part of value that is shown.
+	limit := len(truncated)
+	if truncated != strings.ReplaceAll(value, "\n", " ") {
+		limit -= len("…")
+	}
+	value = truncated
+	spans := make([]search.Span, 0, len(f.Spans))
+	for _, s := range f.Spans {
+		if s.Start >= limit {
+			break
+		}
+		if s.End > limit {
+			s.End = limit
+		}
+		spans = append(spans, s)
+	}
+	return search.Highlight(value, spans, styleHighlight, styleReset)
+}
+
+// padding returns the number of spaces that fill the line of the selected
+// result to the full width.
+func padding(r search.Result, width, nameWidth int) int {
+	n := width - 4 - nameWidth - visibleWidth(r.Bookmark.Summary(), width-nameWidth-4)
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// visibleWidth returns the number of characters of s shown when it is
+// truncated to width.
+func visibleWidth(s string, width int) int {
+	return utf8.RuneCountInString(truncate(s, width))
+}
+
+// truncate shortens s to at most width characters, ending it with an
+// ellipsis if it was shortened. Line breaks are shown as spaces.
+func truncate(s string, width int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if width <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
+
+// wrap splits s into lines of at most width characters.
+func wrap(s string, width int) []string {
+	if width < 1 {
+		width = 1
+	}
+	var lines []string
+	for _, paragraph := range strings.Split(s, "\n") {
+		runes := []rune(paragraph)
+		for len(runes) > width {
+			lines = append(lines, string(runes[:width]))
+			runes = runes[width:]
+		}
+		lines = append(lines, string(runes))
+	}
+	return lines
+}
+
+// scrollLine returns the part of line that fits in width with the cursor
+// at pos visible, and the column of the cursor in it.
+func scrollLine(line []rune, pos, width int) (string, int) {
+	if width < 1 {
+		width = 1
+	}
+	start := 0
+	if pos > width {
+		start = pos - width
+	}
+	end := start + width
+	if end > len(line) {
+		end = len(line)
+	}
+	return string(line[start:end]), pos - start
+}
@@ -0,0 +1,211 @@
+// Package placeholder implements the placeholders that can be used in
+// bookmarked commands.
+//
+// A placeholder is written as {{name}} or {{name:default}} and is replaced
+// by a value when the bookmark is executed.
+package placeholder
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/henrikac/bookmark/internal/shellwords"
+)
+
+// A Placeholder describes a placeholder found in a command.
+type Placeholder struct {
+	// Name is the name of the placeholder.
+	Name string
+	// Default is the value used if no value is given.
+	Default string
+	// HasDefault reports whether the placeholder has a default value.
+	HasDefault bool
+}
+
+// A QuoteFunc quotes a value before it replaces a placeholder. quote is the
+// quote character enclosing the placeholder in the command or 0 if the
+// placeholder is unquoted.
+type QuoteFunc func(value string, quote byte) string
+
+// A MissingError is returned by Expand if values are missing for some of
+// the placeholders.
+type MissingError struct {
+	Names []string
+}
+
+func (e *MissingError) Error() string {
+	return fmt.Sprintf("missing value for placeholder: %s", strings.Join(e.Names, ", "))
+}
+
+var placeholderRe = regexp.MustCompile(`^\{\{\s*([A-Za-z_][A-Za-z0-9_-]*)(?::([^}]*))?\s*\}\}`)
+
+// match is a placeholder found at a specific position in a command.
+type match struct {
+	Placeholder
+	start, end int
+	quote      byte
+}
+
+// A frame is a quoting context. A command substitution starts a new frame,
+// because quotes inside it do not continue the quotes around it.
+type frame struct {
+	// open is the character that started the frame: 0 for the command
+	// itself, ( for $( and ` for a backtick.
+	open byte
+	// quote is the quote character enclosing the current position in the
+	// frame or 0 if it is unquoted.
+	quote byte
+	// depth is the number of unclosed parentheses inside a $( frame.
+	depth int
+}
+
+// scan finds every placeholder in s while keeping track of the shell
+// quoting context each placeholder appears in. A placeholder inside a
+// command substitution is quoted as if it were outside any quotes, even if
+// the substitution itself is double quoted.
+func scan(s string) []match {
+	var matches []match
+	stack := []frame{{}}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		f := &stack[len(stack)-1]
+		switch {
+		case f.quote == '\'' && c == '\'':
+			f.quote = 0
+			continue
+		case f.quote == '\'':
+			// Only the closing quote is special inside single quotes.
+		case c == '\\':
+			i++
+			continue
+		case c == '\'' && f.quote == 0:
+			f.quote = '\''
+			continue
+		case c == '"':
+			if f.quote == 0 {
+				f.quote = '"'
+			} else {
+				f.quote = 0
+			}
+			continue
+		case c == '$' && i+1 < len(s) && s[i+1] == '(':
+			stack = append(stack, frame{open: '('})
+			i++
+			continue
+		case c == '`':
+			if f.open == '`' && f.quote == 0 {
+				stack = stack[:len(stack)-1]
+			} else {
+				stack = append(stack, frame{open: '`'})
+			}
+			continue
+		case f.open == '(' && f.quote == 0 && c == '(':
+			f.depth++
+			continue
+		case f.open == '(' && f.quote == 0 && c == ')':
+			if f.depth > 0 {
+				f.depth--
+			} else {
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		}
+		if c != '{' {
+			continue
+		}
+		m := placeholderRe.FindStringSubmatchIndex(s[i:])
+		if m == nil {
+			continue
+		}
+		p := Placeholder{Name: s[i+m[2] : i+m[3]]}
+		if m[4] >= 0 {
+			p.Default = strings.TrimSpace(s[i+m[4] : i+m[5]])
+			p.HasDefault = true
+		}
+		matches = append(matches, match{Placeholder: p, start: i, end: i + m[1], quote: f.quote})
+		i += m[1] - 1
+	}
+	return matches
+}
+
+// Parse returns the placeholders in s in order of first appearance.
+// A placeholder used more than once is only returned once; the first
+// default given for it is used.
+func Parse(s string) []Placeholder {
+	var placeholders []Placeholder
+	seen := map[string]int{}
+	for _, m := range scan(s) {
+		if i, found := seen[m.Name]; found {
+			if !placeholders[i].HasDefault && m.HasDefault {
+				placeholders[i] = m.Placeholder
+			}
+			continue
+		}
+		seen[m.Name] = len(placeholders)
+		placeholders = append(placeholders, m.Placeholder)
+	}
+	return placeholders
+}
+
+// Expand replaces the placeholders in s with the given values quoted by
+// quote. Placeholders without a value use their default value. A
+// *MissingError is returned if neither is available.
+func Expand(s string, values map[string]string, quote QuoteFunc) (string, error) {
+	defaults := map[string]string{}
+	for _, p := range Parse(s) {
+		if p.HasDefault {
+			defaults[p.Name] = p.Default
+		}
+	}
+	var missing []string
+	var b strings.Builder
+	last := 0
+	for _, m := range scan(s) {
+		value, found := values[m.Name]
+		if !found {
+			value, found = defaults[m.Name]
+		}
+		if !found {
+			if !contains(missing, m.Name) {
+				missing = append(missing, m.Name)
+			}
+			continue
+		}
+		b.WriteString(s[last:m.start])
+		b.WriteString(quote(value, m.quote))
+		last = m.end
+	}
+	if len(missing) > 0 {
+		return "", &MissingError{Names: missing}
+	}
+	b.WriteString(s[last:])
+	return b.String(), nil
+}
+
+// ShellQuote is a QuoteFunc for POSIX shells.
+func ShellQuote(value string, quote byte) string {
+	switch quote {
+	case '\'':
+		return strings.ReplaceAll(value, "'", `'\''`)
+	case '"':
+		var b strings.Builder
+		for _, r := range value {
+			if strings.ContainsRune("\\\"$`", r) {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		}
+		return b.String()
+	}
+	return shellwords.Quote(value)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
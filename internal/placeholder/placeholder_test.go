@@ -0,0 +1,124 @@
+package placeholder_test
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/henrikac/bookmark/internal/placeholder"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		expected []placeholder.Placeholder
+	}{
+		{"none", "ls -al", nil},
+		{"single", "ssh {{host}}", []placeholder.Placeholder{{Name: "host"}}},
+		{
+			"default",
+			"git checkout {{branch:main}}",
+			[]placeholder.Placeholder{{Name: "branch", Default: "main", HasDefault: true}},
+		},
+		{
+			"empty default",
+			"echo {{msg:}}",
+			[]placeholder.Placeholder{{Name: "msg", HasDefault: true}},
+		},
+		{
+			"repeated",
+			"echo {{a}} {{b}} {{a:x}}",
+			[]placeholder.Placeholder{{Name: "a", Default: "x", HasDefault: true}, {Name: "b"}},
+		},
+		{"spaces", "echo {{ name }}", []placeholder.Placeholder{{Name: "name"}}},
+		{"invalid name", "echo {{1abc}} {{}}", nil},
+		{"escaped brace", `echo \{{name}}`, nil},
+		{"go template", `docker ps --format '{{.Names}}'`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := placeholder.Parse(tt.command)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected: %+v\nGot: %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		values   map[string]string
+		expected string
+	}{
+		{"plain", "ssh {{host}}", map[string]string{"host": "example.com"}, "ssh example.com"},
+		{"default", "git checkout {{branch:main}}", nil, "git checkout main"},
+		{"override default", "git checkout {{branch:main}}", map[string]string{"branch": "dev"}, "git checkout dev"},
+		{"repeated", "echo {{a}}{{a}}", map[string]string{"a": "x"}, "echo xx"},
+		{"spaces", "echo {{msg}}", map[string]string{"msg": "hello world"}, "echo 'hello world'"},
+		{"empty", "echo {{msg}}", map[string]string{"msg": ""}, "echo ''"},
+		{"single quote", "echo {{msg}}", map[string]string{"msg": "it's"}, `echo 'it'\''s'`},
+		{"injection", "echo {{msg}}", map[string]string{"msg": "$(rm -rf ~); `id`"}, "echo '$(rm -rf ~); `id`'"},
+		{"inside single quotes", "echo 'say {{msg}}'", map[string]string{"msg": "it's"}, `echo 'say it'\''s'`},
+		{"inside double quotes", `echo "say {{msg}}"`, map[string]string{"msg": `"$HOME" \ ` + "`x`"}, `echo "say \"\$HOME\" \\ \` + "`x\\`" + `"`},
+		{"after quoted string", `echo "a" {{msg}}`, map[string]string{"msg": "b c"}, `echo "a" 'b c'`},
+		{"command substitution", `echo "$(echo {{v}})"`, map[string]string{"v": "x; id"}, `echo "$(echo 'x; id')"`},
+		{"quotes in command substitution", `echo "$(echo "{{v}}") {{v}}"`, map[string]string{"v": "$x"}, `echo "$(echo "\$x") \$x"`},
+		{"nested parentheses", `echo "$( (echo {{v}}) ) {{v}}"`, map[string]string{"v": "a b"}, `echo "$( (echo 'a b') ) a b"`},
+		{"backticks", "echo \"`echo {{v}}` {{v}}\"", map[string]string{"v": "x; id"}, "echo \"`echo 'x; id'` x; id\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := placeholder.Expand(tt.command, tt.values, placeholder.ShellQuote)
+			if err != nil {
+				t.Fatalf("Error: %s", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected: %s\nGot: %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestExpandMissing(t *testing.T) {
+	_, err := placeholder.Expand("scp {{src}} {{host}}:{{dst:/tmp}} {{src}}", nil, placeholder.ShellQuote)
+	var missing *placeholder.MissingError
+	if !errors.As(err, &missing) {
+		t.Fatalf("Expected a MissingError\nGot: %v", err)
+	}
+	expected := []string{"src", "host"}
+	if !reflect.DeepEqual(missing.Names, expected) {
+		t.Errorf("Expected: %v\nGot: %v", expected, missing.Names)
+	}
+}
+
+func TestExpandCommandSubstitution(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+	dir := t.TempDir()
+	for _, command := range []string{`echo "$(echo {{v}})"`, "echo \"`echo {{v}}`\""} {
+		script, err := placeholder.Expand(command, map[string]string{"v": "x; touch pwned"}, placeholder.ShellQuote)
+		if err != nil {
+			t.Fatalf("Error: %s", err)
+		}
+		cmd := exec.Command("sh", "-c", script)
+		cmd.Dir = dir
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("Error: %s", err)
+		}
+		if string(out) != "x; touch pwned\n" {
+			t.Errorf("Expected: %q\nGot: %q", "x; touch pwned\n", out)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "pwned")); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to not run the value", script)
+		}
+	}
+}
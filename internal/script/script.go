@@ -0,0 +1,143 @@
+// Package script inspects the scripts of script bookmarks.
+//
+// A script starts with a shebang line naming its interpreter, e.g.
+// "#!/usr/bin/env bash". Scripts run by a POSIX shell are lexed with
+// package shellwords to tell comments and lines that continue a command
+// apart from the rest of the script.
+package script
+
+import (
+	"path"
+	"strings"
+
+	"github.com/henrikac/bookmark/internal/shellwords"
+)
+
+// Interpreter returns the interpreter named by the shebang line of body and
+// its arguments, or nil if body has no shebang. A leading env and its
+// options are removed, so "#!/usr/bin/env -S python3 -u" returns
+// [python3 -u].
+func Interpreter(body string) []string {
+	if !strings.HasPrefix(body, "#!") {
+		return nil
+	}
+	line := body[2:]
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	argv := strings.Fields(line)
+	if len(argv) == 0 || path.Base(argv[0]) != "env" {
+		return argv
+	}
+	for i, arg := range argv[1:] {
+		if !strings.HasPrefix(arg, "-") && !strings.Contains(arg, "=") {
+			return argv[i+1:]
+		}
+	}
+	return nil
+}
+
+// Name returns the name of the interpreter of body, e.g. bash, or an empty
+// string if body has no shebang.
+func Name(body string) string {
+	argv := Interpreter(body)
+	if len(argv) == 0 {
+		return ""
+	}
+	return path.Base(argv[0])
+}
+
+// Summary returns the first line of body after the shebang that is not
+// blank.
+func Summary(body string) string {
+	for i, line := range strings.Split(body, "\n") {
+		if i == 0 && strings.HasPrefix(line, "#!") {
+			continue
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// posixShells are the interpreters whose scripts are lexed by Lines.
+var posixShells = map[string]bool{
+	"sh":   true,
+	"ash":  true,
+	"bash": true,
+	"dash": true,
+	"ksh":  true,
+	"mksh": true,
+	"zsh":  true,
+}
+
+// A Line is a single line of a script.
+type Line struct {
+	// Number is the line number, starting at 1.
+	Number int
+	// Text is the line without its line break.
+	Text string
+	// Comment is the byte offset in Text where a comment starts, or -1 if
+	// the line has no comment. The shebang line is a comment.
+	Comment int
+	// Continued reports whether the line continues the command of the line
+	// above, after an escaped line break, inside quotes or in the body of a
+	// here-document.
+	Continued bool
+}
+
+// Lines splits body into lines. Comments and continued lines are only
+// found in scripts run by a POSIX shell that can be lexed.
+func Lines(body string) []Line {
+	body = strings.TrimSuffix(body, "\n")
+	texts := strings.Split(body, "\n")
+	lines := make([]Line, len(texts))
+	for i, text := range texts {
+		lines[i] = Line{Number: i + 1, Text: text, Comment: -1}
+	}
+	if strings.HasPrefix(body, "#!") {
+		lines[0].Comment = 0
+	}
+	if !posixShells[Name(body)] {
+		return lines
+	}
+	tokens, err := shellwords.Lex(body)
+	if err != nil {
+		return lines
+	}
+	// The lexer skips comments, so a # outside of every token starts one.
+	covered := make([]bool, len(body))
+	// ends holds the offsets of the line breaks that end a command and
+	// heredocs the offsets of the bodies of here-documents.
+	ends := make(map[int]bool)
+	heredocs := make([]bool, len(body))
+	for _, t := range tokens {
+		for i := t.Pos; i < t.Pos+len(t.Raw); i++ {
+			covered[i] = true
+			heredocs[i] = heredocs[i] || t.Kind == shellwords.HereDoc
+		}
+		switch {
+		case t.Kind == shellwords.Operator && t.Value == "\n":
+			ends[t.Pos] = true
+		case t.Kind == shellwords.HereDoc && strings.HasSuffix(t.Raw, "\n"):
+			ends[t.Pos+len(t.Raw)-1] = true
+		}
+	}
+	offset := 0
+	for i := range lines {
+		if i > 0 {
+			lines[i].Continued = !ends[offset-1] || offset < len(body) && heredocs[offset]
+		}
+		if lines[i].Comment < 0 {
+			for j := 0; j < len(lines[i].Text); j++ {
+				if lines[i].Text[j] == '#' && !covered[offset+j] {
+					lines[i].Comment = j
+					break
+				}
+			}
+		}
+		offset += len(lines[i].Text) + 1
+	}
+	return lines
+}
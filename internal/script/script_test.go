@@ -0,0 +1,115 @@
+package script_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/henrikac/bookmark/internal/script"
+)
+
+func TestInterpreter(t *testing.T) {
+	tests := []struct {
+		body     string
+		expected []string
+		name     string
+	}{
+		{"#!/bin/sh\necho hi\n", []string{"/bin/sh"}, "sh"},
+		{"#! /bin/bash -e\n", []string{"/bin/bash", "-e"}, "bash"},
+		{"#!/usr/bin/env python3\n", []string{"python3"}, "python3"},
+		{"#!/usr/bin/env -S FOO=1 node --no-warnings\n", []string{"node", "--no-warnings"}, "node"},
+		{"#!/usr/bin/env\n", nil, ""},
+		{"echo hi\n", nil, ""},
+	}
+	for _, tt := range tests {
+		if got := script.Interpreter(tt.body); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Expected: %q\nGot: %q", tt.expected, got)
+		}
+		if got := script.Name(tt.body); got != tt.name {
+			t.Errorf("Expected: %s\nGot: %s", tt.name, got)
+		}
+	}
+}
+
+func TestSummary(t *testing.T) {
+	tests := []struct {
+		body     string
+		expected string
+	}{
+		{"#!/bin/sh\n\n  # Deploy the api\nmake deploy\n", "# Deploy the api"},
+		{"echo hi\n", "echo hi"},
+		{"#!/bin/sh\n", ""},
+	}
+	for _, tt := range tests {
+		if got := script.Summary(tt.body); got != tt.expected {
+			t.Errorf("Expected: %s\nGot: %s", tt.expected, got)
+		}
+	}
+}
+
+// render writes every line of lines with its comment in brackets, marking
+// continued lines with a +.
+func render(lines []script.Line) string {
+	var b strings.Builder
+	for i, line := range lines {
+		if line.Number != i+1 {
+			return "wrong line number"
+		}
+		if line.Continued {
+			b.WriteString("+")
+		}
+		if line.Comment < 0 {
+			b.WriteString(line.Text)
+		} else {
+			b.WriteString(line.Text[:line.Comment] + "[" + line.Text[line.Comment:] + "]")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{
+			"comments",
+			"#!/bin/sh\n# Count the arguments\necho \"$#\" '#' a#b # done\n",
+			"[#!/bin/sh]\n[# Count the arguments]\necho \"$#\" '#' a#b [# done]\n",
+		},
+		{
+			"continued lines",
+			"#!/usr/bin/env bash\ndocker run \\\n  --rm image\necho 'a\nb'\necho done\n",
+			"[#!/usr/bin/env bash]\ndocker run \\\n+  --rm image\necho 'a\n+b'\necho done\n",
+		},
+		{
+			"here-document",
+			"#!/bin/sh\ncat <<-EOF # note\n\t# not a comment\n\tEOF\necho done",
+			"[#!/bin/sh]\ncat <<-EOF [# note]\n+\t# not a comment\n+\tEOF\necho done\n",
+		},
+		{
+			"blank lines",
+			"#!/bin/sh\n\necho a\n\n",
+			"[#!/bin/sh]\n\necho a\n\n",
+		},
+		{
+			"other interpreter",
+			"#!/usr/bin/env python3\n# comment\nprint('x')\n",
+			"[#!/usr/bin/env python3]\n# comment\nprint('x')\n",
+		},
+		{
+			"syntax error",
+			"#!/bin/sh\necho 'unterminated # quote\n",
+			"[#!/bin/sh]\necho 'unterminated # quote\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := render(script.Lines(tt.body)); got != tt.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
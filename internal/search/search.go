@@ -0,0 +1,403 @@
+// Package search implements ranked searching of bookmarks by name, command,
+// target, description and tags.
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/henrikac/bookmark/internal/store"
+)
+
+// A Mode selects how a query is matched.
+type Mode int
+
+const (
+	// Fuzzy matches values that contain the characters of the query in
+	// order, but not necessarily next to each other.
+	Fuzzy Mode = iota
+	// Substring matches values that contain the query.
+	Substring
+	// Regex matches values that match the query as a regular expression.
+	Regex
+)
+
+// A Span is the byte range [Start, End) of a match in a value.
+type Span struct {
+	Start, End int
+}
+
+// A Matcher matches a query against values.
+type Matcher interface {
+	// Match returns the score of value and the spans that matched, or
+	// false if value does not match.
+	Match(value string) (score int, spans []Span, ok bool)
+}
+
+// NewMatcher returns a Matcher for query using the given mode. Fuzzy and
+// substring matching ignore case unless query contains an upper case
+// letter.
+func NewMatcher(query string, mode Mode) (Matcher, error) {
+	switch mode {
+	case Fuzzy:
+		return newFuzzyMatcher(query), nil
+	case Substring:
+		return newSubstringMatcher(query), nil
+	case Regex:
+		re, err := regexp.Compile(query)
+		if err != nil {
+			return nil, err
+		}
+		return regexMatcher{re}, nil
+	default:
+		return nil, fmt.Errorf("unknown search mode: %d", mode)
+	}
+}
+
+// A Field is a part of a bookmark that matched a query.
+type Field struct {
+	// Name is the name of the field: name, command, target, description or
+	// tags.
+	Name string
+	// Value is the value of the field. The tags of a bookmark are joined
+	// with spaces.
+	Value string
+	// Spans are the parts of Value that matched.
+	Spans []Span
+}
+
+// A Result is a bookmark that matched a query.
+type Result struct {
+	// Name is the name of the bookmark.
+	Name string
+	// Bookmark is the bookmark that matched.
+	Bookmark store.Bookmark
+	// Score ranks the result, a higher score is a better match.
+	Score int
+	// Fields are the fields of the bookmark that matched, in the order
+	// name, command, target, description and tags.
+	Fields []Field
+}
+
+// Field returns the matched field with the given name and whether it was
+// matched.
+func (r Result) Field(name string) (Field, bool) {
+	for _, f := range r.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// fieldWeights multiply the score of a match in a field, so that a match
+// in the name ranks above a match in the command.
+var fieldWeights = map[string]int{
+	"name":        4,
+	"tags":        3,
+	"description": 2,
+	"command":     1,
+	"target":      1,
+}
+
+// Search matches every bookmark against m and returns the matching
+// bookmarks ranked by score. Bookmarks with the same score are sorted by
+// name. If m is nil every bookmark matches with a score of zero.
+func Search(bookmarks store.BookmarkContainer, m Matcher) []Result {
+	results := make([]Result, 0, len(bookmarks))
+	for name, bookmark := range bookmarks {
+		result := Result{Name: name, Bookmark: bookmark}
+		fields := []Field{
+			{Name: "name", Value: name},
+			{Name: "command", Value: bookmark.Command},
+			{Name: "target", Value: bookmark.Target},
+			{Name: "description", Value: bookmark.Description},
+			{Name: "tags", Value: strings.Join(bookmark.Tags, " ")},
+		}
+		if m == nil {
+			results = append(results, result)
+			continue
+		}
+		for _, f := range fields {
+			if f.Value == "" {
+				continue
+			}
+			score, spans, ok := m.Match(f.Value)
+			if !ok {
+				continue
+			}
+			f.Spans = spans
+			result.Fields = append(result.Fields, f)
+			if score *= fieldWeights[f.Name]; score > result.Score {
+				result.Score = score
+			}
+		}
+		if len(result.Fields) > 0 {
+			results = append(results, result)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Name < results[j].Name
+	})
+	return results
+}
+
+// SummaryField returns the name of the field that holds the summary of b,
+// see store.Bookmark.Summary.
+func SummaryField(b store.Bookmark) string {
+	if b.Kind == store.KindCommand {
+		return "command"
+	}
+	return "target"
+}
+
+// Highlight wraps every span of s in start and end.
+func Highlight(s string, spans []Span, start, end string) string {
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		b.WriteString(s[last:span.Start])
+		b.WriteString(start)
+		b.WriteString(s[span.Start:span.End])
+		b.WriteString(end)
+		last = span.End
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// Scores awarded for matched characters.
+const (
+	scoreMatch       = 16
+	bonusBoundary    = 10
+	bonusConsecutive = 8
+	bonusExact       = 32
+	bonusPrefix      = 16
+	penaltyGap       = 1
+)
+
+// isBoundary reports whether the rune at i in runes starts a word.
+func isBoundary(runes []rune, i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev, r := runes[i-1], runes[i]
+	if !unicode.IsLetter(prev) && !unicode.IsDigit(prev) {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+	return unicode.IsLower(prev) && unicode.IsUpper(r)
+}
+
+// hasUpper reports whether s contains an upper case letter.
+func hasUpper(s string) bool {
+	return strings.IndexFunc(s, unicode.IsUpper) >= 0
+}
+
+// text is a value split into runes, with the byte offset of every rune.
+type text struct {
+	runes   []rune
+	offsets []int
+}
+
+func newText(s string, fold bool) text {
+	t := text{
+		runes:   make([]rune, 0, len(s)),
+		offsets: make([]int, 0, len(s)+1),
+	}
+	for i, r := range s {
+		if fold {
+			r = unicode.ToLower(r)
+		}
+		t.runes = append(t.runes, r)
+		t.offsets = append(t.offsets, i)
+	}
+	t.offsets = append(t.offsets, len(s))
+	return t
+}
+
+type fuzzyMatcher struct {
+	query []rune
+	fold  bool
+}
+
+func newFuzzyMatcher(query string) fuzzyMatcher {
+	fold := !hasUpper(query)
+	return fuzzyMatcher{query: newText(query, fold).runes, fold: fold}
+}
+
+// Match finds the best scoring occurrence of the query as a subsequence of
+// value. Every position where the first character of the query matches is
+// tried, and each greedy forward match is shortened from its end to find
+// the tightest window.
+func (m fuzzyMatcher) Match(value string) (int, []Span, bool) {
+	if len(m.query) == 0 {
+		return 0, nil, false
+	}
+	orig := newText(value, false)
+	t := newText(value, m.fold)
+	best, bestScore := []int(nil), -1
+	for start := range t.runes {
+		if t.runes[start] != m.query[0] {
+			continue
+		}
+		// Match forward from start.
+		q, end := 0, -1
+		for i := start; i < len(t.runes); i++ {
+			if t.runes[i] == m.query[q] {
+				q++
+				if q == len(m.query) {
+					end = i
+					break
+				}
+			}
+		}
+		if end < 0 {
+			break
+		}
+		// Match backward from end to find the shortest window.
+		positions := make([]int, len(m.query))
+		q = len(m.query) - 1
+		for i := end; i >= start && q >= 0; i-- {
+			if t.runes[i] == m.query[q] {
+				positions[q] = i
+				q--
+			}
+		}
+		if score := m.score(orig.runes, positions); score > bestScore {
+			best, bestScore = positions, score
+		}
+	}
+	if best == nil {
+		return 0, nil, false
+	}
+	var spans []Span
+	for _, p := range best {
+		if n := len(spans); n > 0 && spans[n-1].End == t.offsets[p] {
+			spans[n-1].End = t.offsets[p+1]
+		} else {
+			spans = append(spans, Span{t.offsets[p], t.offsets[p+1]})
+		}
+	}
+	if len(best) == len(t.runes) {
+		bestScore += bonusExact
+	}
+	return bestScore, spans, true
+}
+
+func (m fuzzyMatcher) score(runes []rune, positions []int) int {
+	score := 0
+	for i, p := range positions {
+		score += scoreMatch
+		if isBoundary(runes, p) {
+			score += bonusBoundary
+		}
+		if i > 0 {
+			if gap := p - positions[i-1] - 1; gap == 0 {
+				score += bonusConsecutive
+			} else {
+				score -= gap * penaltyGap
+			}
+		}
+	}
+	if positions[0] == 0 {
+		score += bonusPrefix
+	}
+	if score < 1 {
+		score = 1
+	}
+	return score
+}
+
+type substringMatcher struct {
+	query []rune
+	fold  bool
+}
+
+func newSubstringMatcher(query string) substringMatcher {
+	fold := !hasUpper(query)
+	return substringMatcher{query: newText(query, fold).runes, fold: fold}
+}
+
+// Match finds every occurrence of the query in value. The score favours
+// occurrences at the start of a word and values that are mostly made up of
+// the query.
+func (m substringMatcher) Match(value string) (int, []Span, bool) {
+	n := len(m.query)
+	if n == 0 {
+		return 0, nil, false
+	}
+	orig := newText(value, false)
+	t := newText(value, m.fold)
+	var spans []Span
+	score := 0
+	for i := 0; i+n <= len(t.runes); i++ {
+		if !equalRunes(t.runes[i:i+n], m.query) {
+			continue
+		}
+		if spans == nil {
+			score = n * scoreMatch
+			if isBoundary(orig.runes, i) {
+				score += bonusBoundary
+			}
+			if i == 0 {
+				score += bonusPrefix
+			}
+			if n == len(t.runes) {
+				score += bonusExact
+			}
+			// Prefer short values over long ones.
+			score -= (len(t.runes) - n) / 8
+		}
+		spans = append(spans, Span{t.offsets[i], t.offsets[i+n]})
+		i += n - 1
+	}
+	if spans == nil {
+		return 0, nil, false
+	}
+	if score < 1 {
+		score = 1
+	}
+	return score, spans, true
+}
+
+func equalRunes(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type regexMatcher struct {
+	re *regexp.Regexp
+}
+
+// Match finds every non-empty match of the regular expression in value.
+// The score grows with the length of the first match.
+func (m regexMatcher) Match(value string) (int, []Span, bool) {
+	var spans []Span
+	for _, loc := range m.re.FindAllStringIndex(value, -1) {
+		if loc[0] < loc[1] {
+			spans = append(spans, Span{loc[0], loc[1]})
+		}
+	}
+	if spans == nil {
+		return 0, nil, false
+	}
+	score := utf8.RuneCountInString(value[spans[0].Start:spans[0].End]) * scoreMatch
+	if spans[0].Start == 0 {
+		score += bonusPrefix
+	}
+	if spans[0].Start == 0 && spans[0].End == len(value) {
+		score += bonusExact
+	}
+	return score, spans, true
+}
@@ -0,0 +1,113 @@
+package search_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/henrikac/bookmark/internal/search"
+	"github.com/henrikac/bookmark/internal/store"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     search.Mode
+		query    string
+		value    string
+		expected string
+	}{
+		{"fuzzy", search.Fuzzy, "kgp", "kubectl get pods", "[k]ubectl [g]et [p]ods"},
+		{"fuzzy consecutive", search.Fuzzy, "get", "kubectl get pods", "kubectl [get] pods"},
+		{"fuzzy ignores case", search.Fuzzy, "dps", "Docker PS", "[D]ocker [PS]"},
+		{"fuzzy smart case", search.Fuzzy, "Dps", "docker ps", ""},
+		{"fuzzy unicode", search.Fuzzy, "æø", "blåbær og ø", "blåb[æ]r og [ø]"},
+		{"fuzzy no match", search.Fuzzy, "xyz", "kubectl get pods", ""},
+		{"substring", search.Substring, "po", "kubectl get pods --all-pods", "kubectl get [po]ds --all-[po]ds"},
+		{"substring ignores case", search.Substring, "ps", "docker PS", "docker [PS]"},
+		{"substring no match", search.Substring, "kgp", "kubectl get pods", ""},
+		{"regex", search.Regex, `get (pods|svc)`, "kubectl get svc", "kubectl [get svc]"},
+		{"regex empty match", search.Regex, `x*`, "kubectl", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := search.NewMatcher(tt.query, tt.mode)
+			if err != nil {
+				t.Fatalf("Error: %s", err)
+			}
+			score, spans, ok := m.Match(tt.value)
+			got := ""
+			if ok {
+				got = search.Highlight(tt.value, spans, "[", "]")
+				if score <= 0 {
+					t.Errorf("Expected a positive score\nGot: %d", score)
+				}
+			}
+			if got != tt.expected {
+				t.Errorf("Expected: %s\nGot: %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestMatchScore(t *testing.T) {
+	tests := []struct {
+		mode   search.Mode
+		query  string
+		better string
+		worse  string
+	}{
+		{search.Fuzzy, "gp", "get pods", "go to prod"},
+		{search.Fuzzy, "dep", "deploy", "docker exec prod"},
+		{search.Fuzzy, "pods", "pods", "list-pods"},
+		{search.Substring, "log", "logs", "docker logs"},
+		{search.Substring, "log", "logs", "catalog"},
+		{search.Regex, "p.d", "pods", "a pod"},
+	}
+	for _, tt := range tests {
+		m, err := search.NewMatcher(tt.query, tt.mode)
+		if err != nil {
+			t.Fatalf("Error: %s", err)
+		}
+		better, _, _ := m.Match(tt.better)
+		worse, _, _ := m.Match(tt.worse)
+		if better <= worse {
+			t.Errorf("Expected %q (%d) to score higher than %q (%d) for %q", tt.better, better, tt.worse, worse, tt.query)
+		}
+	}
+}
+
+func TestNewMatcherInvalidRegex(t *testing.T) {
+	if _, err := search.NewMatcher("(", search.Regex); err == nil {
+		t.Error("Expected an error for an invalid regular expression")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	bookmarks := store.BookmarkContainer{
+		"pods":   {Command: "kubectl get pods"},
+		"deploy": {Command: "make deploy", Description: "deploy the pods"},
+		"ps":     {Command: "docker ps", Tags: []string{"pods"}},
+		"list":   {Command: "ls"},
+	}
+	m, err := search.NewMatcher("pods", search.Substring)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	results := search.Search(bookmarks, m)
+	var names []string
+	for _, r := range results {
+		names = append(names, r.Name)
+	}
+	expected := []string{"pods", "ps", "deploy"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected: %v\nGot: %v", expected, names)
+	}
+	if f, ok := results[0].Field("command"); !ok || f.Spans[0] != (search.Span{Start: 12, End: 16}) {
+		t.Errorf("Expected the command of %s to match\nGot: %+v", results[0].Name, results[0].Fields)
+	}
+
+	all := search.Search(bookmarks, nil)
+	if len(all) != len(bookmarks) || all[0].Name != "deploy" {
+		t.Errorf("Expected every bookmark sorted by name\nGot: %+v", all)
+	}
+}
@@ -0,0 +1,266 @@
+// Package shellhist reads the history files of interactive shells.
+//
+// Bash history files contain a command per line, optionally preceded by a
+// timestamp comment if HISTTIMEFORMAT is set. Zsh history files contain a
+// command per line, with a line break inside a command escaped by a
+// backslash, optionally in the extended format ": <start>:<elapsed>;<command>".
+// Fish history files are YAML-like lists of "- cmd: <command>" entries.
+package shellhist
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// A Format is the format of a history file.
+type Format int
+
+const (
+	Bash Format = iota
+	Zsh
+	Fish
+)
+
+// formats maps the name of a shell to the format of its history file.
+var formats = map[string]Format{
+	"bash": Bash,
+	"zsh":  Zsh,
+	"fish": Fish,
+}
+
+// FormatOf returns the history format of the shell with the given name.
+func FormatOf(shell string) (Format, error) {
+	f, ok := formats[shell]
+	if !ok {
+		return 0, fmt.Errorf("unable to read the history of shell \"%s\", expected one of: bash, fish, zsh", shell)
+	}
+	return f, nil
+}
+
+// String returns the name of the shell that writes history files in the
+// format.
+func (f Format) String() string {
+	for name, format := range formats {
+		if format == f {
+			return name
+		}
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// DefaultPath returns the path of the history file the shell writes by
+// default, respecting HISTFILE for bash and zsh.
+func DefaultPath(f Format) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	switch f {
+	case Bash:
+		if path := os.Getenv("HISTFILE"); path != "" {
+			return path, nil
+		}
+		return filepath.Join(home, ".bash_history"), nil
+	case Zsh:
+		if path := os.Getenv("HISTFILE"); path != "" {
+			return path, nil
+		}
+		if dir := os.Getenv("ZDOTDIR"); dir != "" {
+			home = dir
+		}
+		return filepath.Join(home, ".zsh_history"), nil
+	case Fish:
+		dataHome := os.Getenv("XDG_DATA_HOME")
+		if dataHome == "" {
+			dataHome = filepath.Join(home, ".local", "share")
+		}
+		session := os.Getenv("fish_history")
+		if session == "" {
+			session = "fish"
+		}
+		return filepath.Join(dataHome, "fish", session+"_history"), nil
+	}
+	return "", fmt.Errorf("unknown history format: %s", f)
+}
+
+// ReadFile reads the commands of the history file at path, oldest first.
+func ReadFile(path string, f Format) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Read(file, f)
+}
+
+// Read reads the commands of a history file from r, oldest first.
+func Read(r io.Reader, f Format) ([]string, error) {
+	lines, err := readLines(r)
+	if err != nil {
+		return nil, err
+	}
+	switch f {
+	case Bash:
+		return parseBash(lines), nil
+	case Zsh:
+		return parseZsh(lines), nil
+	case Fish:
+		return parseFish(lines), nil
+	}
+	return nil, fmt.Errorf("unknown history format: %s", f)
+}
+
+// readLines reads the lines of r without their line breaks. Lines of any
+// length are supported.
+func readLines(r io.Reader) ([][]byte, error) {
+	var lines [][]byte
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			line = bytes.TrimSuffix(line, []byte("\n"))
+			lines = append(lines, bytes.TrimSuffix(line, []byte("\r")))
+		}
+		if errors.Is(err, io.EOF) {
+			return lines, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// bashTimestampRe matches the timestamp comments of a bash history file.
+var bashTimestampRe = regexp.MustCompile(`^#\d+$`)
+
+// parseBash parses a bash history file. If it contains timestamps, every
+// command spans the lines up to the next timestamp, which keeps multi-line
+// commands saved with the lithist option together.
+func parseBash(lines [][]byte) []string {
+	timestamps := len(lines) > 0 && bashTimestampRe.Match(lines[0])
+	var commands []string
+	var command []string
+	flush := func() {
+		if len(command) > 0 {
+			commands = append(commands, strings.Join(command, "\n"))
+			command = nil
+		}
+	}
+	for _, line := range lines {
+		if !timestamps {
+			if len(line) > 0 {
+				commands = append(commands, string(line))
+			}
+			continue
+		}
+		if bashTimestampRe.Match(line) {
+			flush()
+			continue
+		}
+		command = append(command, string(line))
+	}
+	flush()
+	return commands
+}
+
+// zshExtendedRe matches the prefix of a command in the extended history
+// format.
+var zshExtendedRe = regexp.MustCompile(`^: *\d+:\d+;`)
+
+// parseZsh parses a zsh history file in the plain or extended format.
+func parseZsh(lines [][]byte) []string {
+	var commands []string
+	var command []byte
+	continued := false
+	for _, line := range lines {
+		line = unmetafy(line)
+		if !continued {
+			if loc := zshExtendedRe.FindIndex(line); loc != nil {
+				line = line[loc[1]:]
+			}
+			command = nil
+		}
+		// A line break inside a command is escaped with a backslash.
+		continued = bytes.HasSuffix(line, []byte(`\`))
+		if continued {
+			line = line[:len(line)-1]
+		}
+		command = append(command, line...)
+		if continued {
+			command = append(command, '\n')
+			continue
+		}
+		if len(command) > 0 {
+			commands = append(commands, string(command))
+		}
+	}
+	if continued && len(command) > 0 {
+		commands = append(commands, string(bytes.TrimSuffix(command, []byte("\n"))))
+	}
+	return commands
+}
+
+// zshMeta is the byte zsh uses to escape special bytes in its history file.
+// The escaped byte follows it xor 32.
+const zshMeta = 0x83
+
+// unmetafy undoes the escaping of special bytes by zsh.
+func unmetafy(line []byte) []byte {
+	if bytes.IndexByte(line, zshMeta) < 0 {
+		return line
+	}
+	out := make([]byte, 0, len(line))
+	for i := 0; i < len(line); i++ {
+		if line[i] == zshMeta && i+1 < len(line) {
+			i++
+			out = append(out, line[i]^32)
+			continue
+		}
+		out = append(out, line[i])
+	}
+	return out
+}
+
+// parseFish parses a fish history file. Only the commands are read; the
+// timestamps and paths of the entries are ignored.
+func parseFish(lines [][]byte) []string {
+	var commands []string
+	for _, line := range lines {
+		if bytes.HasPrefix(line, []byte("- cmd: ")) {
+			commands = append(commands, unescapeFish(string(line[len("- cmd: "):])))
+		}
+	}
+	return commands
+}
+
+// unescapeFish decodes a command as written by fish, which escapes
+// backslashes and line breaks.
+func unescapeFish(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			switch s[i+1] {
+			case '\\':
+				b.WriteByte('\\')
+				i++
+				continue
+			case 'n':
+				b.WriteByte('\n')
+				i++
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
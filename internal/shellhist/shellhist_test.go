@@ -0,0 +1,81 @@
+package shellhist_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/henrikac/bookmark/internal/shellhist"
+)
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   shellhist.Format
+		input    string
+		expected []string
+	}{
+		{
+			"bash",
+			shellhist.Bash,
+			"ls -al\n\ngit status\r\necho 'a b'\n",
+			[]string{"ls -al", "git status", "echo 'a b'"},
+		},
+		{
+			"bash timestamps",
+			shellhist.Bash,
+			"#1650000000\nls -al\n#1650000001\nfor f in *; do\n  echo $f\ndone\n",
+			[]string{"ls -al", "for f in *; do\n  echo $f\ndone"},
+		},
+		{
+			"zsh",
+			shellhist.Zsh,
+			"ls -al\ngit status\n",
+			[]string{"ls -al", "git status"},
+		},
+		{
+			"zsh extended",
+			shellhist.Zsh,
+			": 1650000000:0;ls -al\n: 1650000001:3;for f in *; do\\\n  echo $f\\\ndone\n: 1650000002:0;echo a;b\n",
+			[]string{"ls -al", "for f in *; do\n  echo $f\ndone", "echo a;b"},
+		},
+		{
+			"zsh metafied",
+			shellhist.Zsh,
+			": 1650000000:0;echo \xc3\x83\xa3\n",
+			[]string{"echo Ã"},
+		},
+		{
+			"fish",
+			shellhist.Fish,
+			"- cmd: ls -al\n  when: 1650000000\n- cmd: echo \\\\n\\nworld\n  when: 1650000001\n  paths:\n    - /tmp\n",
+			[]string{"ls -al", "echo \\n\nworld"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := shellhist.Read(strings.NewReader(tt.input), tt.format)
+			if err != nil {
+				t.Fatalf("Error: %s", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected: %q\nGot: %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		f, err := shellhist.FormatOf(shell)
+		if err != nil {
+			t.Errorf("Error: %s", err)
+		}
+		if f.String() != shell {
+			t.Errorf("Expected: %s\nGot: %s", shell, f)
+		}
+	}
+	if _, err := shellhist.FormatOf("pwsh"); err == nil {
+		t.Error("Expected an error for a shell without a supported history")
+	}
+}
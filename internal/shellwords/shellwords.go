@@ -0,0 +1,543 @@
+// Package shellwords implements a lexer for POSIX shell command lines.
+//
+// The lexer splits a command line into words, operators and redirections the
+// way a POSIX shell such as bash would. Quotes and escapes are removed from
+// words while expansions like $HOME or $(date) are kept as written, so the
+// lexer can be used to validate commands and to run simple commands without a
+// shell.
+package shellwords
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// A TokenKind identifies the kind of a Token.
+type TokenKind int
+
+const (
+	// Word is a word such as a command name or an argument.
+	Word TokenKind = iota
+	// Operator is a control operator such as |, && or ;. A newline is
+	// an operator as well.
+	Operator
+	// Redirect is a redirection operator such as >, 2>&1 or <<.
+	Redirect
+	// HereDoc is the body of a here-document.
+	HereDoc
+)
+
+func (k TokenKind) String() string {
+	switch k {
+	case Word:
+		return "word"
+	case Operator:
+		return "operator"
+	case Redirect:
+		return "redirect"
+	case HereDoc:
+		return "here-document"
+	}
+	return fmt.Sprintf("TokenKind(%d)", int(k))
+}
+
+// A Token is a single lexical unit of a command line.
+type Token struct {
+	// Kind is the kind of the token.
+	Kind TokenKind
+	// Value is the value of the token. For words quotes and escapes have
+	// been removed while expansions are kept as written. For here-documents
+	// it is the body of the document.
+	Value string
+	// Raw is the token as it was written.
+	Raw string
+	// Pos is the byte offset of the token in the command line.
+	Pos int
+	// Expands reports whether a shell would expand the word, e.g. because
+	// it contains a parameter expansion, a command substitution or a glob.
+	Expands bool
+}
+
+// A SyntaxError describes a command line that cannot be lexed or that is
+// not well-formed.
+type SyntaxError struct {
+	// Pos is the byte offset of the error in the command line.
+	Pos int
+	// Msg describes the error.
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Pos, e.Msg)
+}
+
+// Highlight returns the line of the command line s that contains the error
+// followed by a line with a caret pointing at the error.
+func (e *SyntaxError) Highlight(s string) string {
+	pos := e.Pos
+	if pos > len(s) {
+		pos = len(s)
+	}
+	lineStart := strings.LastIndexByte(s[:pos], '\n') + 1
+	lineEnd := strings.IndexByte(s[pos:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(s)
+	} else {
+		lineEnd += pos
+	}
+	col := utf8.RuneCountInString(s[lineStart:pos])
+	return fmt.Sprintf("%s\n%s^ %s", s[lineStart:lineEnd], strings.Repeat(" ", col), e.Msg)
+}
+
+// operators lists the control and redirection operators, longest first.
+var operators = []string{
+	"&>>", "<<<", "<<-",
+	"&>", "<<", "<>", "<&", ">>", ">&", ">|", "||", "|&", "&&", ";;",
+	"|", "&", ";", "(", ")", "<", ">",
+}
+
+// isRedirect reports whether op is a redirection operator.
+func isRedirect(op string) bool {
+	return op[0] == '<' || op[0] == '>' || strings.HasPrefix(op, "&>")
+}
+
+// isMeta reports whether c ends an unquoted word.
+func isMeta(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '|', '&', ';', '(', ')', '<', '>':
+		return true
+	}
+	return false
+}
+
+// heredoc is a here-document whose body has not been read yet.
+type heredoc struct {
+	delim     string
+	stripTabs bool
+}
+
+type lexer struct {
+	input  string
+	pos    int
+	tokens []Token
+	// heredocs are the here-documents whose bodies start after the next
+	// newline.
+	heredocs []heredoc
+	// heredocOp is set while the delimiter of a here-document is expected.
+	heredocOp string
+}
+
+// Lex splits the command line s into tokens.
+func Lex(s string) ([]Token, error) {
+	l := &lexer{input: s}
+	for {
+		l.skipBlanks()
+		if l.pos >= len(s) {
+			break
+		}
+		var err error
+		switch c := s[l.pos]; {
+		case c == '#':
+			end := strings.IndexByte(s[l.pos:], '\n')
+			if end < 0 {
+				l.pos = len(s)
+			} else {
+				l.pos += end
+			}
+		case c == '\n':
+			l.emit(Token{Kind: Operator, Value: "\n", Raw: "\n", Pos: l.pos})
+			l.pos++
+			err = l.lexHeredocs()
+		case isMeta(c):
+			err = l.lexOperator("")
+		default:
+			err = l.lexWord()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if l.heredocOp != "" {
+		return nil, &SyntaxError{Pos: len(s), Msg: "missing here-document delimiter after " + l.heredocOp}
+	}
+	if len(l.heredocs) > 0 {
+		return nil, &SyntaxError{Pos: len(s), Msg: fmt.Sprintf("unterminated here-document %q", l.heredocs[0].delim)}
+	}
+	return l.tokens, nil
+}
+
+func (l *lexer) emit(t Token) {
+	l.tokens = append(l.tokens, t)
+}
+
+// skipBlanks skips spaces, tabs and line continuations.
+func (l *lexer) skipBlanks() {
+	for l.pos < len(l.input) {
+		switch {
+		case l.input[l.pos] == ' ' || l.input[l.pos] == '\t':
+			l.pos++
+		case strings.HasPrefix(l.input[l.pos:], "\\\n"):
+			l.pos += 2
+		default:
+			return
+		}
+	}
+}
+
+// lexOperator lexes the operator at the current position. fd is the file
+// descriptor number written directly before a redirection, if any.
+func (l *lexer) lexOperator(fd string) error {
+	start := l.pos - len(fd)
+	for _, op := range operators {
+		if !strings.HasPrefix(l.input[l.pos:], op) {
+			continue
+		}
+		if fd != "" && !isRedirect(op) {
+			continue
+		}
+		if l.heredocOp != "" {
+			return &SyntaxError{Pos: start, Msg: fmt.Sprintf("unexpected %q, expected here-document delimiter", op)}
+		}
+		l.pos += len(op)
+		kind := Operator
+		if isRedirect(op) {
+			kind = Redirect
+		}
+		if op == "<<" || op == "<<-" {
+			l.heredocOp = op
+		}
+		l.emit(Token{Kind: kind, Value: fd + op, Raw: l.input[start:l.pos], Pos: start})
+		return nil
+	}
+	// Only reachable with a file descriptor number in front of a
+	// character that is not a redirection, which lexWord never does.
+	return &SyntaxError{Pos: l.pos, Msg: fmt.Sprintf("unexpected %q", l.input[l.pos])}
+}
+
+// lexWord lexes the word at the current position.
+func (l *lexer) lexWord() error {
+	s := l.input
+	start := l.pos
+
+	// A word made of digits directly followed by < or > is the file
+	// descriptor of a redirection, e.g. 2>&1.
+	digits := start
+	for digits < len(s) && s[digits] >= '0' && s[digits] <= '9' {
+		digits++
+	}
+	if digits > start && digits < len(s) && (s[digits] == '<' || s[digits] == '>') && l.heredocOp == "" {
+		l.pos = digits
+		return l.lexOperator(s[start:digits])
+	}
+
+	var val strings.Builder
+	expands := false
+	braceOpen := false
+	for l.pos < len(s) && !isMeta(s[l.pos]) {
+		c := s[l.pos]
+		switch {
+		case c == '\\':
+			switch {
+			case l.pos+1 >= len(s):
+				val.WriteByte('\\')
+				l.pos++
+			case s[l.pos+1] == '\n':
+				l.pos += 2
+			default:
+				_, size := utf8.DecodeRuneInString(s[l.pos+1:])
+				val.WriteString(s[l.pos+1 : l.pos+1+size])
+				l.pos += 1 + size
+			}
+		case c == '\'':
+			end := strings.IndexByte(s[l.pos+1:], '\'')
+			if end < 0 {
+				return &SyntaxError{Pos: l.pos, Msg: "unterminated single quote"}
+			}
+			val.WriteString(s[l.pos+1 : l.pos+1+end])
+			l.pos += end + 2
+		case c == '"':
+			exp, err := l.lexDoubleQuoted(&val)
+			if err != nil {
+				return err
+			}
+			expands = expands || exp
+		case c == '$' && strings.HasPrefix(s[l.pos:], "$'"):
+			if err := l.lexANSIQuoted(&val); err != nil {
+				return err
+			}
+		case c == '$' && strings.HasPrefix(s[l.pos:], `$"`):
+			l.pos++
+			exp, err := l.lexDoubleQuoted(&val)
+			if err != nil {
+				return err
+			}
+			expands = expands || exp
+		case c == '$' || c == '`':
+			raw, exp, err := l.lexExpansion()
+			if err != nil {
+				return err
+			}
+			val.WriteString(raw)
+			expands = expands || exp
+		default:
+			switch c {
+			case '*', '?', '[':
+				expands = true
+			case '~':
+				expands = expands || l.pos == start
+			case '{':
+				braceOpen = true
+			case '}':
+				expands = expands || braceOpen
+			}
+			val.WriteByte(c)
+			l.pos++
+		}
+	}
+	word := Token{Kind: Word, Value: val.String(), Raw: s[start:l.pos], Pos: start, Expands: expands}
+	if l.heredocOp != "" {
+		l.heredocs = append(l.heredocs, heredoc{
+			delim:     word.Value,
+			stripTabs: l.heredocOp == "<<-",
+		})
+		l.heredocOp = ""
+		// The delimiter of a here-document is never expanded.
+		word.Expands = false
+	}
+	l.emit(word)
+	return nil
+}
+
+// lexDoubleQuoted lexes the double quoted string at the current position
+// and writes its value to val. It reports whether the string contains an
+// expansion.
+func (l *lexer) lexDoubleQuoted(val *strings.Builder) (bool, error) {
+	s := l.input
+	start := l.pos
+	expands := false
+	l.pos++
+	for l.pos < len(s) {
+		switch c := s[l.pos]; c {
+		case '"':
+			l.pos++
+			return expands, nil
+		case '\\':
+			if l.pos+1 < len(s) && strings.IndexByte("$`\"\\\n", s[l.pos+1]) >= 0 {
+				if s[l.pos+1] != '\n' {
+					val.WriteByte(s[l.pos+1])
+				}
+				l.pos += 2
+				continue
+			}
+			val.WriteByte(c)
+			l.pos++
+		case '$', '`':
+			raw, exp, err := l.lexExpansion()
+			if err != nil {
+				return false, err
+			}
+			val.WriteString(raw)
+			expands = expands || exp
+		default:
+			val.WriteByte(c)
+			l.pos++
+		}
+	}
+	return false, &SyntaxError{Pos: start, Msg: "unterminated double quote"}
+}
+
+// ansiEscapes maps the escape sequences of ANSI-C quoted strings to the
+// characters they represent.
+var ansiEscapes = map[byte]string{
+	'a': "\a", 'b': "\b", 'e': "\x1b", 'E': "\x1b", 'f': "\f", 'n': "\n",
+	'r': "\r", 't': "\t", 'v': "\v", '\\': "\\", '\'': "'", '"': "\"", '?': "?",
+}
+
+// lexANSIQuoted lexes the ANSI-C quoted string ($'...') at the current
+// position and writes its value to val.
+func (l *lexer) lexANSIQuoted(val *strings.Builder) error {
+	s := l.input
+	start := l.pos
+	l.pos += 2
+	for l.pos < len(s) {
+		c := s[l.pos]
+		if c == '\'' {
+			l.pos++
+			return nil
+		}
+		if c != '\\' || l.pos+1 >= len(s) {
+			val.WriteByte(c)
+			l.pos++
+			continue
+		}
+		if esc, found := ansiEscapes[s[l.pos+1]]; found {
+			val.WriteString(esc)
+		} else {
+			val.WriteString(s[l.pos : l.pos+2])
+		}
+		l.pos += 2
+	}
+	return &SyntaxError{Pos: start, Msg: "unterminated ANSI-C quote"}
+}
+
+// lexExpansion lexes the parameter expansion, command substitution or
+// arithmetic expansion starting with $ or ` at the current position. It
+// returns the expansion as written and reports whether it is an actual
+// expansion rather than a literal dollar sign.
+func (l *lexer) lexExpansion() (string, bool, error) {
+	s := l.input
+	start := l.pos
+	if s[l.pos] == '`' {
+		end, err := l.skipBackquoted(l.pos + 1)
+		if err != nil {
+			return "", false, err
+		}
+		l.pos = end
+		return s[start:end], true, nil
+	}
+	l.pos++
+	if l.pos >= len(s) {
+		return "$", false, nil
+	}
+	switch c := s[l.pos]; {
+	case c == '(':
+		end, err := l.skipParens(l.pos + 1)
+		if err != nil {
+			return "", false, err
+		}
+		l.pos = end
+	case c == '{':
+		end, err := l.skipBraces(l.pos + 1)
+		if err != nil {
+			return "", false, err
+		}
+		l.pos = end
+	case isNameStart(c):
+		for l.pos < len(s) && isNameChar(s[l.pos]) {
+			l.pos++
+		}
+	case c >= '0' && c <= '9' || strings.IndexByte("@*#?$!-", c) >= 0:
+		l.pos++
+	default:
+		return "$", false, nil
+	}
+	return s[start:l.pos], true, nil
+}
+
+// skipParens returns the position after the parenthesis closing the one
+// just before i, skipping quoted strings and nested expansions.
+func (l *lexer) skipParens(i int) (int, error) {
+	return l.skipBalanced(i, '(', ')', "unterminated command substitution")
+}
+
+// skipBraces returns the position after the brace closing the one just
+// before i, skipping quoted strings and nested expansions.
+func (l *lexer) skipBraces(i int) (int, error) {
+	return l.skipBalanced(i, '{', '}', "unterminated parameter expansion")
+}
+
+func (l *lexer) skipBalanced(i int, open, close byte, msg string) (int, error) {
+	s := l.input
+	start := i - 1
+	depth := 1
+	for i < len(s) {
+		switch c := s[i]; c {
+		case '\\':
+			i += 2
+			continue
+		case '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return 0, &SyntaxError{Pos: i, Msg: "unterminated single quote"}
+			}
+			i += end + 2
+			continue
+		case '"', '`', '$':
+			sub := &lexer{input: s, pos: i}
+			var err error
+			if c == '"' {
+				_, err = sub.lexDoubleQuoted(&strings.Builder{})
+			} else {
+				_, _, err = sub.lexExpansion()
+			}
+			if err != nil {
+				return 0, err
+			}
+			if sub.pos > i {
+				i = sub.pos
+				continue
+			}
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return i + 1, nil
+			}
+		}
+		i++
+	}
+	return 0, &SyntaxError{Pos: start, Msg: msg}
+}
+
+// skipBackquoted returns the position after the backquote closing the one
+// just before i.
+func (l *lexer) skipBackquoted(i int) (int, error) {
+	s := l.input
+	start := i - 1
+	for i < len(s) {
+		switch s[i] {
+		case '\\':
+			i += 2
+			continue
+		case '`':
+			return i + 1, nil
+		}
+		i++
+	}
+	return 0, &SyntaxError{Pos: start, Msg: "unterminated command substitution"}
+}
+
+// lexHeredocs reads the bodies of the pending here-documents, which start
+// at the current position.
+func (l *lexer) lexHeredocs() error {
+	s := l.input
+	for _, h := range l.heredocs {
+		start := l.pos
+		var body strings.Builder
+		for {
+			if l.pos >= len(s) {
+				return &SyntaxError{Pos: start, Msg: fmt.Sprintf("unterminated here-document %q", h.delim)}
+			}
+			end := strings.IndexByte(s[l.pos:], '\n')
+			var line string
+			if end < 0 {
+				line = s[l.pos:]
+				l.pos = len(s)
+			} else {
+				line = s[l.pos : l.pos+end]
+				l.pos += end + 1
+			}
+			if h.stripTabs {
+				line = strings.TrimLeft(line, "\t")
+			}
+			if line == h.delim {
+				break
+			}
+			body.WriteString(line)
+			body.WriteByte('\n')
+		}
+		l.emit(Token{Kind: HereDoc, Value: body.String(), Raw: s[start:l.pos], Pos: start})
+	}
+	l.heredocs = nil
+	return nil
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || c >= '0' && c <= '9'
+}
@@ -0,0 +1,342 @@
+package shellwords_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/henrikac/bookmark/internal/shellwords"
+)
+
+// tok is a compact description of a token used in the tests.
+type tok struct {
+	kind    shellwords.TokenKind
+	value   string
+	expands bool
+}
+
+func word(value string) tok        { return tok{kind: shellwords.Word, value: value} }
+func expanding(value string) tok   { return tok{kind: shellwords.Word, value: value, expands: true} }
+func op(value string) tok          { return tok{kind: shellwords.Operator, value: value} }
+func redirect(value string) tok    { return tok{kind: shellwords.Redirect, value: value} }
+func heredocBody(value string) tok { return tok{kind: shellwords.HereDoc, value: value} }
+
+func TestLex(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []tok
+	}{
+		{"empty", "", nil},
+		{"blank", " \t ", nil},
+		{"words", "ls -al /tmp", []tok{word("ls"), word("-al"), word("/tmp")}},
+		{"extra spaces", "  echo   hello  ", []tok{word("echo"), word("hello")}},
+		{"single quotes", `echo 'hello world' 'it''s'`, []tok{word("echo"), word("hello world"), word("its")}},
+		{"single quotes keep escapes", `echo '\n $HOME'`, []tok{word("echo"), word(`\n $HOME`)}},
+		{"double quotes", `echo "hello world"`, []tok{word("echo"), word("hello world")}},
+		{"double quote escapes", `echo "a\"b\\c\$d\e"`, []tok{word("echo"), word(`a"b\c$d\e`)}},
+		{"double quote expansion", `echo "home: $HOME"`, []tok{word("echo"), expanding("home: $HOME")}},
+		{"adjacent quotes", `echo a"b c"'d e'f`, []tok{word("echo"), word("ab cd ef")}},
+		{"empty quotes", `echo "" ''`, []tok{word("echo"), word(""), word("")}},
+		{"escapes", `echo hello\ world \"quoted\" back\\slash`, []tok{word("echo"), word("hello world"), word(`"quoted"`), word(`back\slash`)}},
+		{"trailing backslash", `echo a\`, []tok{word("echo"), word(`a\`)}},
+		{"line continuation", "echo a \\\n b", []tok{word("echo"), word("a"), word("b")}},
+		{"line continuation in word", "ec\\\nho", []tok{word("echo")}},
+		{"unicode", "echo héllo 'wörld' \\ü", []tok{word("echo"), word("héllo"), word("wörld"), word("ü")}},
+		{"ansi-c quotes", `echo $'a\tb\n\'c'`, []tok{word("echo"), word("a\tb\n'c")}},
+		{"locale quotes", `echo $"hello"`, []tok{word("echo"), word("hello")}},
+		{"variable", "echo $HOME ${USER:-me} $1 $@ $?", []tok{word("echo"), expanding("$HOME"), expanding("${USER:-me}"), expanding("$1"), expanding("$@"), expanding("$?")}},
+		{"lone dollar", "echo $ a$", []tok{word("echo"), word("$"), word("a$")}},
+		{"command substitution", "echo $(date +%s)", []tok{word("echo"), expanding("$(date +%s)")}},
+		{"nested substitution", `echo "$(echo "$(pwd)" | tr a b)"`, []tok{word("echo"), expanding(`$(echo "$(pwd)" | tr a b)`)}},
+		{"substitution with parens", "echo $(echo ')' \\) (x))", []tok{word("echo"), expanding(`$(echo ')' \) (x))`)}},
+		{"arithmetic", "echo $((1 + (2 * 3)))", []tok{word("echo"), expanding("$((1 + (2 * 3)))")}},
+		{"backquotes", "echo `date` \"`pwd`\"", []tok{word("echo"), expanding("`date`"), expanding("`pwd`")}},
+		{"glob", "ls *.go file? [ab]", []tok{word("ls"), expanding("*.go"), expanding("file?"), expanding("[ab]")}},
+		{"quoted glob", `ls "*.go" \*`, []tok{word("ls"), word("*.go"), word("*")}},
+		{"tilde", "cd ~/src a~b", []tok{word("cd"), expanding("~/src"), word("a~b")}},
+		{"brace expansion", "echo {a,b} {c", []tok{word("echo"), expanding("{a,b}"), word("{c")}},
+		{"placeholder", "ssh {{host}}", []tok{word("ssh"), expanding("{{host}}")}},
+		{"pipe", "ls|wc -l", []tok{word("ls"), op("|"), word("wc"), word("-l")}},
+		{"operators", "a && b || c; d & e |& f", []tok{
+			word("a"), op("&&"), word("b"), op("||"), word("c"), op(";"),
+			word("d"), op("&"), word("e"), op("|&"), word("f"),
+		}},
+		{"subshell", "(cd /tmp; ls)", []tok{op("("), word("cd"), word("/tmp"), op(";"), word("ls"), op(")")}},
+		{"newline", "a\nb", []tok{word("a"), op("\n"), word("b")}},
+		{"redirects", "cmd >out 2>&1 <in >>log &>all", []tok{
+			word("cmd"), redirect(">"), word("out"), redirect("2>&"), word("1"),
+			redirect("<"), word("in"), redirect(">>"), word("log"), redirect("&>"), word("all"),
+		}},
+		{"digits are not always a fd", "echo 2 >out 2", []tok{word("echo"), word("2"), redirect(">"), word("out"), word("2")}},
+		{"quoted operators", `echo "a|b" 'c;d' e\&f`, []tok{word("echo"), word("a|b"), word("c;d"), word("e&f")}},
+		{"comment", "ls # list files\npwd", []tok{word("ls"), op("\n"), word("pwd")}},
+		{"hash in word", "echo a#b", []tok{word("echo"), word("a#b")}},
+		{"here-string", "cat <<< hello", []tok{word("cat"), redirect("<<<"), word("hello")}},
+		{"here-document", "cat <<EOF\nhello $USER\nEOF\necho done", []tok{
+			word("cat"), redirect("<<"), word("EOF"), op("\n"),
+			heredocBody("hello $USER\n"), word("echo"), word("done"),
+		}},
+		{"here-document strip tabs", "cat <<-'END'\n\tindented\n\tEND", []tok{
+			word("cat"), redirect("<<-"), word("END"), op("\n"), heredocBody("indented\n"),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := shellwords.Lex(tt.input)
+			if err != nil {
+				t.Fatalf("Error: %s", err)
+			}
+			var got []tok
+			for _, token := range tokens {
+				got = append(got, tok{kind: token.Kind, value: token.Value, expands: token.Expands})
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected: %+v\nGot: %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLexRawAndPos(t *testing.T) {
+	input := `echo "a b" 2>&1 | x`
+	tokens, err := shellwords.Lex(input)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	expected := []string{"echo", `"a b"`, "2>&", "1", "|", "x"}
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens\nGot: %d", len(expected), len(tokens))
+	}
+	for i, token := range tokens {
+		if token.Raw != expected[i] {
+			t.Errorf("Expected raw: %s\nGot: %s", expected[i], token.Raw)
+		}
+		if input[token.Pos:token.Pos+len(token.Raw)] != token.Raw {
+			t.Errorf("Token %q does not match the input at position %d", token.Raw, token.Pos)
+		}
+	}
+}
+
+func TestLexErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		pos   int
+	}{
+		{"single quote", "echo 'hello", 5},
+		{"double quote", `echo "hello`, 5},
+		{"escaped double quote", `echo "hello\"`, 5},
+		{"ansi-c quote", `echo $'hello`, 5},
+		{"command substitution", "echo $(date", 6},
+		{"nested command substitution", "echo $(echo $(date)", 6},
+		{"quote in substitution", `echo $(echo ")`, 12},
+		{"parameter expansion", "echo ${HOME", 6},
+		{"backquote", "echo `date", 5},
+		{"here-document", "cat <<EOF\nhello", 10},
+		{"here-document without body", "cat <<EOF", 9},
+		{"here-document delimiter", "cat <<", 6},
+		{"here-document operator", "cat << |", 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := shellwords.Lex(tt.input)
+			var syntaxErr *shellwords.SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Expected a SyntaxError\nGot: %v", err)
+			}
+			if syntaxErr.Pos != tt.pos {
+				t.Errorf("Expected position: %d\nGot: %d (%s)", tt.pos, syntaxErr.Pos, syntaxErr.Msg)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input string
+		valid bool
+	}{
+		{"", true},
+		{"ls -al", true},
+		{"ls | wc -l", true},
+		{"make && ./run || echo failed", true},
+		{"sleep 1 &", true},
+		{"a; b;", true},
+		{"(cd /tmp && ls) > out", true},
+		{"ls &&\n  pwd", true},
+		{"echo hi >&2", true},
+		{"> file", true},
+		{"f() { echo hi; }; f", true},
+		{"case $1 in a) echo a;; *) echo other;; esac", true},
+		{"cat <<EOF\nhello\nEOF", true},
+		{"echo $(ls | wc -l)", true},
+		{"| ls", false},
+		{"ls |", false},
+		{"ls && && pwd", false},
+		{"ls ||", false},
+		{"; ls", false},
+		{"ls ;; pwd", true},
+		{"ls >", false},
+		{"ls > | wc", false},
+		{"(ls", false},
+		{"ls)", false},
+		{"(ls |)", false},
+		{"echo (", false},
+		{"echo 'unterminated", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			err := shellwords.Check(tt.input)
+			if tt.valid && err != nil {
+				t.Errorf("Expected %q to be valid\nGot: %s", tt.input, err)
+			}
+			if !tt.valid && err == nil {
+				t.Errorf("Expected %q to be invalid", tt.input)
+			}
+		})
+	}
+}
+
+func TestSyntaxErrorHighlight(t *testing.T) {
+	input := "echo ok\nls | | wc"
+	err := shellwords.Check(input)
+	var syntaxErr *shellwords.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("Expected a SyntaxError\nGot: %v", err)
+	}
+	expected := "ls | | wc\n     ^ unexpected \"|\""
+	if got := syntaxErr.Highlight(input); got != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	words, err := shellwords.Split(`git commit -m "fix: it's done" --author='A B'`)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	expected := []string{"git", "commit", "-m", "fix: it's done", "--author=A B"}
+	if !reflect.DeepEqual(words, expected) {
+		t.Errorf("Expected: %q\nGot: %q", expected, words)
+	}
+	if _, err := shellwords.Split("ls | wc"); err == nil {
+		t.Error("Expected an error for a command with operators")
+	}
+}
+
+func TestSimple(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`echo "Hello world"`, []string{"echo", "Hello world"}},
+		{"git status --short", []string{"git", "status", "--short"}},
+		{`printf '%s\n' a`, []string{"printf", `%s\n`, "a"}},
+		{"", nil},
+		{"echo $HOME", nil},
+		{"ls *.go", nil},
+		{"ls | wc", nil},
+		{"cd /tmp", nil},
+		{"exit 3", nil},
+		{"FOO=bar env", nil},
+		{"echo ~", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			words, ok := shellwords.Simple(tt.input)
+			if ok != (tt.expected != nil) {
+				t.Fatalf("Expected simple: %t\nGot: %t", tt.expected != nil, ok)
+			}
+			if !reflect.DeepEqual(words, tt.expected) {
+				t.Errorf("Expected: %q\nGot: %q", tt.expected, words)
+			}
+		})
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", "''"},
+		{"simple", "simple"},
+		{"a/b.c-d_e:f,g+h@i%j=k", "a/b.c-d_e:f,g+h@i%j=k"},
+		{"hello world", "'hello world'"},
+		{"it's", `'it'\''s'`},
+		{"$(rm -rf ~)", "'$(rm -rf ~)'"},
+		{"*", "'*'"},
+		{"~", "'~'"},
+		{"a\nb", "'a\nb'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := shellwords.Quote(tt.input); got != tt.expected {
+				t.Errorf("Expected: %s\nGot: %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func FuzzLex(f *testing.F) {
+	seeds := []string{
+		"ls -al", `echo "a $(b "c") d" 'e' f\ g`, "a && b || c | d; e &",
+		"cat <<EOF\nx\nEOF\n", "echo ${a:-$(b)} `c` $((1+2))", "x 2>&1 >>f <g",
+		"echo $'\\t'", "(a; b)", "# comment\n", "\\\n", "echo {{host}}",
+	}
+	for _, s := range seeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		tokens, err := shellwords.Lex(s)
+		if err != nil {
+			var syntaxErr *shellwords.SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Expected a SyntaxError\nGot: %v", err)
+			}
+			if syntaxErr.Pos < 0 || syntaxErr.Pos > len(s) {
+				t.Fatalf("Error position %d out of range for %q", syntaxErr.Pos, s)
+			}
+			_ = syntaxErr.Highlight(s)
+			return
+		}
+		last := -1
+		for _, token := range tokens {
+			if token.Pos <= last || token.Pos+len(token.Raw) > len(s) {
+				t.Fatalf("Token %q has invalid position %d in %q", token.Raw, token.Pos, s)
+			}
+			if s[token.Pos:token.Pos+len(token.Raw)] != token.Raw {
+				t.Fatalf("Token %q does not match the input at position %d in %q", token.Raw, token.Pos, s)
+			}
+			last = token.Pos
+		}
+		_ = shellwords.Check(s)
+	})
+}
+
+func FuzzQuote(f *testing.F) {
+	seeds := []string{"", "a", "hello world", "it's", `"\$`, "a\nb", "~", "*"}
+	for _, s := range seeds {
+		f.Add(s, "second")
+	}
+	f.Fuzz(func(t *testing.T, a, b string) {
+		for _, s := range []string{a, b} {
+			words, err := shellwords.Split(shellwords.Quote(s))
+			if err != nil {
+				t.Fatalf("Error splitting quoted %q: %s", s, err)
+			}
+			if len(words) != 1 || words[0] != s {
+				t.Fatalf("Expected: [%q]\nGot: %q", s, words)
+			}
+		}
+		joined := shellwords.Join([]string{a, b})
+		words, err := shellwords.Split(joined)
+		if err != nil {
+			t.Fatalf("Error splitting %q: %s", joined, err)
+		}
+		if !reflect.DeepEqual(words, []string{a, b}) {
+			t.Fatalf("Expected: %q\nGot: %q", []string{a, b}, words)
+		}
+	})
+}
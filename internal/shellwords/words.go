@@ -0,0 +1,192 @@
+package shellwords
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Check reports whether s is a well-formed command line. Besides lexing s it
+// makes sure that operators and redirections are used where a shell expects
+// them and that parentheses are balanced.
+func Check(s string) error {
+	tokens, err := Lex(s)
+	if err != nil {
+		return err
+	}
+	// command is true if the current command has at least one word.
+	command := false
+	// pending is the control operator that still needs a command after it.
+	var pending *Token
+	var parens []Token
+	// cases is the number of open case statements, whose patterns end
+	// with an unbalanced ")".
+	cases := 0
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch t.Kind {
+		case Word:
+			if !command {
+				switch t.Raw {
+				case "case":
+					cases++
+				case "esac":
+					cases--
+				}
+			}
+			command = true
+			pending = nil
+		case Redirect:
+			if i+1 >= len(tokens) || tokens[i+1].Kind != Word {
+				return &SyntaxError{Pos: t.Pos, Msg: fmt.Sprintf("missing target for redirection %q", t.Value)}
+			}
+			command = true
+			pending = nil
+			i++
+		case HereDoc:
+		case Operator:
+			switch t.Value {
+			case "(":
+				if i+1 < len(tokens) && tokens[i+1].Value == ")" && command {
+					// A function definition such as f() { ...; }.
+					command = false
+					i++
+					continue
+				}
+				if command {
+					return &SyntaxError{Pos: t.Pos, Msg: `unexpected "("`}
+				}
+				parens = append(parens, t)
+			case ")":
+				if len(parens) == 0 {
+					if cases > 0 && command {
+						// The end of a case pattern.
+						command = false
+						continue
+					}
+					return &SyntaxError{Pos: t.Pos, Msg: `unexpected ")"`}
+				}
+				if pending != nil {
+					return &SyntaxError{Pos: t.Pos, Msg: fmt.Sprintf("missing command after %q", pending.Value)}
+				}
+				parens = parens[:len(parens)-1]
+				command = true
+			case "\n", ";;":
+				if pending != nil {
+					// A line break may follow a control operator.
+					continue
+				}
+				command = false
+			case ";", "&":
+				if !command {
+					return &SyntaxError{Pos: t.Pos, Msg: fmt.Sprintf("unexpected %q", t.Value)}
+				}
+				command = false
+			default:
+				if !command {
+					return &SyntaxError{Pos: t.Pos, Msg: fmt.Sprintf("unexpected %q", t.Value)}
+				}
+				command = false
+				pending = &tokens[i]
+			}
+		}
+	}
+	if pending != nil {
+		return &SyntaxError{Pos: len(s), Msg: fmt.Sprintf("missing command after %q", pending.Value)}
+	}
+	if len(parens) > 0 {
+		return &SyntaxError{Pos: parens[len(parens)-1].Pos, Msg: `unclosed "("`}
+	}
+	return nil
+}
+
+// Split splits s into words like a shell would, but without performing any
+// expansions. An error is returned if s contains operators or redirections.
+func Split(s string) ([]string, error) {
+	tokens, err := Lex(s)
+	if err != nil {
+		return nil, err
+	}
+	words := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		if t.Kind != Word {
+			return nil, &SyntaxError{Pos: t.Pos, Msg: fmt.Sprintf("unexpected %s %q", t.Kind, t.Value)}
+		}
+		words = append(words, t.Value)
+	}
+	return words, nil
+}
+
+// assignmentRe matches words that assign a shell variable.
+var assignmentRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*\+?=`)
+
+// builtins are shell keywords and builtins that cannot be executed
+// without a shell.
+var builtins = map[string]bool{
+	"!": true, "{": true, "}": true, "[[": true, "]]": true,
+	".": true, ":": true, "alias": true, "bg": true, "break": true,
+	"builtin": true, "case": true, "cd": true, "command": true,
+	"continue": true, "declare": true, "do": true, "done": true,
+	"elif": true, "else": true, "esac": true, "eval": true, "exec": true,
+	"exit": true, "export": true, "fg": true, "fi": true, "for": true,
+	"function": true, "if": true, "jobs": true, "let": true, "local": true,
+	"read": true, "readonly": true, "return": true, "select": true,
+	"set": true, "shift": true, "source": true, "then": true, "time": true,
+	"trap": true, "type": true, "typeset": true, "ulimit": true,
+	"umask": true, "unalias": true, "unset": true, "until": true,
+	"wait": true, "while": true,
+}
+
+// Simple reports whether s is a simple command that can be executed without
+// a shell and returns its words if so. A simple command consists of words
+// only, none of which a shell would expand, and does not start with a
+// variable assignment or a shell keyword or builtin.
+func Simple(s string) ([]string, bool) {
+	tokens, err := Lex(s)
+	if err != nil || len(tokens) == 0 {
+		return nil, false
+	}
+	words := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		if t.Kind != Word || t.Expands {
+			return nil, false
+		}
+		words = append(words, t.Value)
+	}
+	first := tokens[0].Raw
+	if assignmentRe.MatchString(first) || builtins[first] {
+		return nil, false
+	}
+	return words, true
+}
+
+// Quote quotes s so that a POSIX shell reads it as a single word with the
+// value s.
+func Quote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if strings.IndexFunc(s, needsQuoting) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Join quotes every word in words and joins them with spaces. Splitting the
+// result with Split returns words.
+func Join(words []string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		quoted[i] = Quote(w)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// needsQuoting reports whether r has a special meaning to a POSIX shell.
+func needsQuoting(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return false
+	}
+	return !strings.ContainsRune("-_./:,+@%=", r)
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// atomicFile is the subset of *os.File used by writeFileAtomic.
+type atomicFile interface {
+	io.Writer
+	Name() string
+	Chmod(mode os.FileMode) error
+	Sync() error
+	Close() error
+}
+
+// createTemp creates the temporary file written by writeFileAtomic.
+// It is a variable so tests can simulate failures partway through a write.
+var createTemp = func(dir, pattern string) (atomicFile, error) {
+	return os.CreateTemp(dir, pattern)
+}
+
+// writeFileAtomic writes data to filename without ever leaving a partially
+// written file behind. The data is written to a temporary file in the same
+// directory which is synced to disk and then renamed over filename.
+// The permissions of an existing file are preserved, otherwise perm is used.
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) (err error) {
+	if resolved, err := filepath.EvalSymlinks(filename); err == nil {
+		filename = resolved
+	}
+	info, err := os.Stat(filename)
+	if err == nil {
+		perm = info.Mode().Perm()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	dir := filepath.Dir(filename)
+	f, err := createTemp(dir, "."+filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return err
+	}
+	closed := false
+	defer func() {
+		if err != nil {
+			if !closed {
+				_ = f.Close()
+			}
+			_ = os.Remove(f.Name())
+		}
+	}()
+	if _, err = f.Write(data); err != nil {
+		return err
+	}
+	if err = f.Chmod(perm); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	closed = true
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(f.Name(), filename); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir flushes the directory entry of a renamed file to disk.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		// Directories cannot be opened for syncing on Windows.
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
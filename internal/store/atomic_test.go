@@ -0,0 +1,156 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+var errInjected = errors.New("injected failure")
+
+// failingFile wraps a temporary file and fails at a configurable point.
+type failingFile struct {
+	*os.File
+	// failAfter is the number of bytes written before Write fails.
+	// A negative value disables write failures.
+	failAfter int
+	failSync  bool
+	failClose bool
+}
+
+func (f *failingFile) Write(b []byte) (int, error) {
+	if f.failAfter >= 0 && len(b) > f.failAfter {
+		n, _ := f.File.Write(b[:f.failAfter])
+		return n, errInjected
+	}
+	return f.File.Write(b)
+}
+
+func (f *failingFile) Sync() error {
+	if f.failSync {
+		return errInjected
+	}
+	return f.File.Sync()
+}
+
+func (f *failingFile) Close() error {
+	err := f.File.Close()
+	if f.failClose {
+		return errInjected
+	}
+	return err
+}
+
+func TestWriteFileAtomicFailures(t *testing.T) {
+	tests := []struct {
+		name string
+		file failingFile
+	}{
+		{"write fails immediately", failingFile{failAfter: 0}},
+		{"write fails partway", failingFile{failAfter: 5}},
+		{"sync fails", failingFile{failAfter: -1, failSync: true}},
+		{"close fails", failingFile{failAfter: -1, failClose: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "bookmarks.json")
+			original := []byte(`{"version":3,"bookmarks":{"ls":{"command":"ls"}}}`)
+			if err := os.WriteFile(path, original, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			oldCreateTemp := createTemp
+			defer func() { createTemp = oldCreateTemp }()
+			createTemp = func(dir, pattern string) (atomicFile, error) {
+				f, err := os.CreateTemp(dir, pattern)
+				if err != nil {
+					return nil, err
+				}
+				ff := tt.file
+				ff.File = f
+				return &ff, nil
+			}
+
+			err := writeFileAtomic(path, []byte(`{"version":3,"bookmarks":{}}`), 0644)
+			if !errors.Is(err, errInjected) {
+				t.Fatalf("Expected the injected error\nGot: %v", err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != string(original) {
+				t.Errorf("Expected the original store to be untouched\nGot: %s", data)
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Errorf("Expected the temporary file to be removed\nFound %d files", len(entries))
+			}
+		})
+	}
+}
+
+func TestWriteFileAtomicPreservesPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix permissions are not supported on windows")
+	}
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+	if err := os.WriteFile(path, []byte(`{}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(path, []byte(`{"ls":"ls"}`), 0644); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected permissions: %v\nGot: %v", os.FileMode(0600), info.Mode().Perm())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"ls":"ls"}` {
+		t.Errorf("Expected: {\"ls\":\"ls\"}\nGot: %s", data)
+	}
+}
+
+func TestWriteFileAtomicFollowsSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.json")
+	link := filepath.Join(dir, "bookmarks.json")
+	if err := os.WriteFile(target, []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks are not supported: %s", err)
+	}
+	if err := writeFileAtomic(link, []byte(`{"ls":"ls"}`), 0644); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	info, err := os.Lstat(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		t.Error("Expected the symlink to be kept")
+	}
+	data, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"ls":"ls"}` {
+		t.Errorf("Expected: {\"ls\":\"ls\"}\nGot: %s", data)
+	}
+}
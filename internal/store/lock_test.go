@@ -0,0 +1,135 @@
+package store_test
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/henrikac/bookmark/internal/store"
+)
+
+const bookmarksPerWriter = 10
+
+// addBookmarks adds bookmarksPerWriter bookmarks prefixed with prefix, one
+// transaction at a time.
+func addBookmarks(path, prefix string) error {
+	s := &store.BookmarkFileStore{Path: path}
+	for i := 0; i < bookmarksPerWriter; i++ {
+		name := fmt.Sprintf("%s-%d", prefix, i)
+		err := s.Modify(func(bookmarks store.BookmarkContainer) error {
+			bookmarks[name] = store.Bookmark{Command: "echo " + name}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assertBookmarks checks that every bookmark added by writers writers is
+// present in the store at path.
+func assertBookmarks(t *testing.T, path string, writers int) {
+	t.Helper()
+	bookmarks, err := (&store.BookmarkFileStore{Path: path}).Load()
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	expected := writers * bookmarksPerWriter
+	if len(bookmarks) != expected {
+		t.Errorf("Expected %d bookmarks\nFound: %d", expected, len(bookmarks))
+	}
+	for w := 0; w < writers; w++ {
+		for i := 0; i < bookmarksPerWriter; i++ {
+			name := fmt.Sprintf("writer%d-%d", w, i)
+			if _, found := bookmarks[name]; !found {
+				t.Errorf("Lost bookmark: %s", name)
+			}
+		}
+	}
+}
+
+func TestModifyConcurrentWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+	const writers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			errs <- addBookmarks(path, fmt.Sprintf("writer%d", w))
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Error: %s", err)
+		}
+	}
+	assertBookmarks(t, path, writers)
+}
+
+func TestModifyConcurrentProcesses(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping multi-process test in short mode")
+	}
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+	const writers = 8
+	cmds := make([]*exec.Cmd, 0, writers)
+	for w := 0; w < writers; w++ {
+		c := exec.Command(os.Args[0], "-test.run=^TestModifyHelperProcess$")
+		c.Env = append(
+			os.Environ(),
+			"BOOKMARK_TEST_STORE="+path,
+			"BOOKMARK_TEST_WRITER="+strconv.Itoa(w),
+		)
+		c.Stdout = os.Stdout
+		c.Stderr = os.Stderr
+		if err := c.Start(); err != nil {
+			t.Fatal(err)
+		}
+		cmds = append(cmds, c)
+	}
+	for _, c := range cmds {
+		if err := c.Wait(); err != nil {
+			t.Errorf("Writer failed: %s", err)
+		}
+	}
+	assertBookmarks(t, path, writers)
+}
+
+// TestModifyHelperProcess is run as a separate writer process by
+// TestModifyConcurrentProcesses.
+func TestModifyHelperProcess(t *testing.T) {
+	path := os.Getenv("BOOKMARK_TEST_STORE")
+	if path == "" {
+		return
+	}
+	if err := addBookmarks(path, "writer"+os.Getenv("BOOKMARK_TEST_WRITER")); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func TestModifyErrorDiscardsChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+	s := &store.BookmarkFileStore{Path: path}
+	if err := addBookmarks(path, "writer0"); err != nil {
+		t.Fatal(err)
+	}
+	expectedErr := fmt.Errorf("abort")
+	err := s.Modify(func(bookmarks store.BookmarkContainer) error {
+		delete(bookmarks, "writer0-0")
+		return expectedErr
+	})
+	if err != expectedErr {
+		t.Errorf("Expected: %v\nGot: %v", expectedErr, err)
+	}
+	assertBookmarks(t, path, 1)
+}
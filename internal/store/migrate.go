@@ -0,0 +1,183 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// CurrentVersion is the version of the store format written by
+// BookmarkFileStore.Update.
+const CurrentVersion = 3
+
+// A Migration upgrades a store document from one version to the next.
+type Migration struct {
+	// From is the version the migration upgrades from. The migrated
+	// document has version From+1.
+	From int
+	// Description is a short summary of what the migration does.
+	Description string
+	// Migrate returns the upgraded document along with a human readable
+	// list of the changes that were made.
+	Migrate func(data []byte) ([]byte, []string, error)
+}
+
+// A MigrationStep describes the changes made by a single migration.
+type MigrationStep struct {
+	From        int
+	To          int
+	Description string
+	Changes     []string
+}
+
+// A MigrationResult describes the outcome of migrating a store document.
+type MigrationResult struct {
+	// From is the version of the document before it was migrated.
+	From int
+	// To is the version of the document after it was migrated.
+	To int
+	// Steps lists the migrations that were applied in order.
+	Steps []MigrationStep
+	// Backup is the path to the backup of the pre-migration store. It is
+	// empty if no backup was written.
+	Backup string
+}
+
+var migrations = map[int]Migration{}
+
+// RegisterMigration registers a migration. It panics if a migration from
+// the same version has already been registered.
+func RegisterMigration(m Migration) {
+	if m.Migrate == nil {
+		panic("store: RegisterMigration called with nil Migrate func")
+	}
+	if _, dup := migrations[m.From]; dup {
+		panic(fmt.Sprintf("store: migration from version %d registered twice", m.From))
+	}
+	migrations[m.From] = m
+}
+
+// Version reports the version of a store document.
+//
+// Documents written by version 3 and later carry an explicit version field.
+// Older documents are detected by their shape: version 1 maps bookmark names
+// directly to commands while version 2 maps them to bookmark records.
+func Version(data []byte) (int, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return 0, err
+	}
+	if v, found := raw["version"]; found {
+		var version int
+		if err := json.Unmarshal(v, &version); err == nil {
+			return version, nil
+		}
+	}
+	for _, value := range raw {
+		if !isJSONString(value) {
+			return 2, nil
+		}
+	}
+	return 1, nil
+}
+
+// Migrate upgrades a store document to CurrentVersion by applying the
+// registered migrations one version at a time.
+func Migrate(data []byte) ([]byte, *MigrationResult, error) {
+	version, err := Version(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if version > CurrentVersion {
+		return nil, nil, fmt.Errorf(
+			"store version %d is newer than the supported version %d",
+			version,
+			CurrentVersion,
+		)
+	}
+	result := &MigrationResult{From: version, To: version}
+	for result.To < CurrentVersion {
+		m, found := migrations[result.To]
+		if !found {
+			return nil, nil, fmt.Errorf("no migration from store version %d", result.To)
+		}
+		migrated, changes, err := m.Migrate(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("migrating store from version %d: %w", m.From, err)
+		}
+		data = migrated
+		result.Steps = append(result.Steps, MigrationStep{
+			From:        m.From,
+			To:          m.From + 1,
+			Description: m.Description,
+			Changes:     changes,
+		})
+		result.To = m.From + 1
+	}
+	return data, result, nil
+}
+
+// isJSONString reports whether value is a json encoded string.
+func isJSONString(value json.RawMessage) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(value), []byte(`"`))
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// migrateV1ToV2 converts every name → command entry into a bookmark record.
+func migrateV1ToV2(data []byte) ([]byte, []string, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, nil, err
+	}
+	bc := make(BookmarkContainer, len(raw))
+	changes := []string{}
+	for _, name := range sortedKeys(raw) {
+		var command string
+		if err := json.Unmarshal(raw[name], &command); err != nil {
+			return nil, nil, fmt.Errorf("bookmark %q: %w", name, err)
+		}
+		bc[name] = Bookmark{Command: command}
+		changes = append(changes, fmt.Sprintf("convert %q to a bookmark record", name))
+	}
+	b, err := json.Marshal(bc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b, changes, nil
+}
+
+// migrateV2ToV3 wraps the bookmark records in a versioned document.
+func migrateV2ToV3(data []byte) ([]byte, []string, error) {
+	var bc BookmarkContainer
+	if err := json.Unmarshal(data, &bc); err != nil {
+		return nil, nil, err
+	}
+	b, err := json.Marshal(storeFile{Version: 3, Bookmarks: bc})
+	if err != nil {
+		return nil, nil, err
+	}
+	return b, []string{"add version field"}, nil
+}
+
+func init() {
+	RegisterMigration(Migration{
+		From:        1,
+		Description: "convert commands to bookmark records",
+		Migrate:     migrateV1ToV2,
+	})
+	RegisterMigration(Migration{
+		From:        2,
+		Description: "add an explicit store version",
+		Migrate:     migrateV2ToV3,
+	})
+}
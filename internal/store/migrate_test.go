@@ -0,0 +1,130 @@
+package store_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/henrikac/bookmark/internal/store"
+)
+
+func TestVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected int
+	}{
+		{"empty", `{}`, 1},
+		{"flat", `{"ls":"ls -al"}`, 1},
+		{"flat named version", `{"version":"echo v1"}`, 1},
+		{"structured", `{"ls":{"command":"ls -al"}}`, 2},
+		{"structured named version", `{"version":{"command":"echo v1"}}`, 2},
+		{"versioned", `{"version":3,"bookmarks":{}}`, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, err := store.Version([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("Error: %s", err)
+			}
+			if version != tt.expected {
+				t.Errorf("Expected: %d\nGot: %d", tt.expected, version)
+			}
+		})
+	}
+}
+
+func TestMigrateFromV1(t *testing.T) {
+	data, result, err := store.Migrate([]byte(`{"ls":"ls -al","hi":"echo hi"}`))
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if result.From != 1 || result.To != store.CurrentVersion {
+		t.Errorf("Expected migration from 1 to %d\nGot: %d to %d", store.CurrentVersion, result.From, result.To)
+	}
+	if len(result.Steps) != store.CurrentVersion-1 {
+		t.Fatalf("Expected %d steps\nGot: %d", store.CurrentVersion-1, len(result.Steps))
+	}
+	if len(result.Steps[0].Changes) != 2 {
+		t.Errorf("Expected 2 changes in the first step\nGot: %v", result.Steps[0].Changes)
+	}
+	version, err := store.Version(data)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if version != store.CurrentVersion {
+		t.Errorf("Expected version: %d\nGot: %d", store.CurrentVersion, version)
+	}
+}
+
+func TestMigrateNewerVersion(t *testing.T) {
+	_, _, err := store.Migrate([]byte(`{"version":999,"bookmarks":{}}`))
+	if err == nil {
+		t.Error("Expected an error when migrating a newer store")
+	}
+}
+
+func TestRegisterMigrationDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected RegisterMigration to panic on a duplicate version")
+		}
+	}()
+	store.RegisterMigration(store.Migration{
+		From: 1,
+		Migrate: func(data []byte) ([]byte, []string, error) {
+			return data, nil, nil
+		},
+	})
+}
+
+func TestBookmarkFileStoreMigrate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+	legacy := []byte(`{"ls":"ls -al"}`)
+	if err := os.WriteFile(path, legacy, 0666); err != nil {
+		t.Fatal(err)
+	}
+	s := &store.BookmarkFileStore{Path: path}
+
+	result, err := s.Migrate(true)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if len(result.Steps) == 0 {
+		t.Error("Expected the dry run to report changes")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(legacy) {
+		t.Errorf("Expected the dry run to leave the store untouched\nGot: %s", data)
+	}
+
+	result, err = s.Migrate(false)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	backup, err := os.ReadFile(result.Backup)
+	if err != nil {
+		t.Fatalf("Expected a backup: %s", err)
+	}
+	if string(backup) != string(legacy) {
+		t.Errorf("Expected backup: %s\nGot: %s", legacy, backup)
+	}
+	bookmarks, err := s.Load()
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if bookmarks["ls"].Command != "ls -al" {
+		t.Errorf("Expected: ls -al\nGot: %s", bookmarks["ls"].Command)
+	}
+
+	result, err = s.Migrate(false)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if len(result.Steps) != 0 {
+		t.Errorf("Expected no changes for an up to date store\nGot: %v", result.Steps)
+	}
+}
@@ -3,8 +3,13 @@ package store
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/henrikac/bookmark/internal/filelock"
 	"github.com/spf13/viper"
 )
 
@@ -25,16 +30,196 @@ type BookmarkStoreLoadUpdater interface {
 	BookmarkStoreUpdater
 }
 
-// BookmarkContainer
-type BookmarkContainer = map[string]string
+// BookmarkStoreModifier is the interface that wraps the Modify method.
+//
+// Modify calls the given function with the current bookmarks and stores
+// the modified bookmarks afterwards as a single transaction. Nothing is
+// stored if the function returns an error.
+type BookmarkStoreModifier interface {
+	Modify(func(BookmarkContainer) error) error
+}
+
+// BookmarkStoreLoadModifier is the interface that wraps the Load
+// and Modify methods.
+type BookmarkStoreLoadModifier interface {
+	BookmarkStoreLoader
+	BookmarkStoreModifier
+}
+
+// BookmarkStoreMigrator is the interface that wraps the Migrate method.
+type BookmarkStoreMigrator interface {
+	Migrate(dryRun bool) (*MigrationResult, error)
+}
+
+// BookmarkStoreScripter is the interface that wraps the ScriptDir method.
+//
+// ScriptDir returns the directory that holds the script files of the
+// script bookmarks in the store.
+type BookmarkStoreScripter interface {
+	ScriptDir() string
+}
+
+// BookmarkStoreLoadScripter is the interface that wraps the Load and
+// ScriptDir methods.
+type BookmarkStoreLoadScripter interface {
+	BookmarkStoreLoader
+	BookmarkStoreScripter
+}
+
+// BookmarkStore is the interface that groups the Load, Modify, ScriptDir
+// and HistoryPath methods needed to manage and run bookmarks of every
+// kind.
+type BookmarkStore interface {
+	BookmarkStoreLoadModifier
+	BookmarkStoreScripter
+	BookmarkStoreHistorian
+}
+
+// BookmarkStoreHistorian is the interface that wraps the HistoryPath
+// method.
+//
+// HistoryPath returns the path to the file that records the executions of
+// the bookmarks in the store.
+type BookmarkStoreHistorian interface {
+	HistoryPath() string
+}
+
+// BookmarkStoreLoadHistorian is the interface that wraps the Load and
+// HistoryPath methods.
+type BookmarkStoreLoadHistorian interface {
+	BookmarkStoreLoader
+	BookmarkStoreHistorian
+}
+
+// A Kind is the kind of thing a bookmark points to.
+type Kind string
+
+const (
+	// KindCommand is a command. Bookmarks without a kind are commands.
+	KindCommand Kind = ""
+	// KindDir is a directory, stored in the Target of the bookmark.
+	KindDir Kind = "dir"
+	// KindURL is a URL, stored in the Target of the bookmark.
+	KindURL Kind = "url"
+	// KindFile is a file, stored in the Target of the bookmark.
+	KindFile Kind = "file"
+	// KindScript is a script. The Target of the bookmark is the name of the
+	// script file in the script directory of the store.
+	KindScript Kind = "script"
+)
+
+// Kinds are the kinds of bookmarks.
+var Kinds = []Kind{KindCommand, KindDir, KindURL, KindFile, KindScript}
+
+// String returns the name of the kind.
+func (k Kind) String() string {
+	if k == KindCommand {
+		return "command"
+	}
+	return string(k)
+}
+
+// ParseKind returns the kind with the given name. An empty name is a
+// command.
+func ParseKind(name string) (Kind, error) {
+	for _, k := range Kinds {
+		if name == k.String() || name == string(k) {
+			return k, nil
+		}
+	}
+	return "", fmt.Errorf("unknown kind \"%s\"", name)
+}
+
+// A Bookmark describes a single saved bookmark.
+type Bookmark struct {
+	// Kind is the kind of the bookmark. It is empty for commands.
+	Kind Kind `json:"kind,omitempty"`
+	// Command is the command that is executed when the bookmark is run.
+	Command string `json:"command"`
+	// Target is what a bookmark that is not a command points to, e.g. the
+	// directory of a KindDir bookmark. Directories and files may start with
+	// ~ and refer to environment variables.
+	Target string `json:"target,omitempty"`
+	// Description is an optional human readable description of the bookmark.
+	Description string `json:"description,omitempty"`
+	// Shell is the name of the shell that runs the command, e.g. bash or
+	// python. If it is empty the configured default shell is used.
+	Shell string `json:"shell,omitempty"`
+	// Cwd is the directory the command is run in. It may start with ~ and
+	// refer to environment variables. If it is empty the command is run in
+	// the current directory.
+	Cwd string `json:"cwd,omitempty"`
+	// Env holds environment variables that are set for the command in
+	// addition to the current environment.
+	Env map[string]string `json:"env,omitempty"`
+	// Tags is an optional list of tags used to group bookmarks.
+	Tags []string `json:"tags,omitempty"`
+	// CreatedAt is the time the bookmark was added.
+	CreatedAt time.Time `json:"createdAt"`
+	// LastUsedAt is the last time the bookmark was executed. It is nil if
+	// the bookmark has never been executed.
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	// RunCount is the number of times the bookmark has been executed.
+	RunCount int `json:"runCount,omitempty"`
+}
+
+// Summary returns the command of a command bookmark and the target of any
+// other bookmark.
+func (b Bookmark) Summary() string {
+	if b.Kind == KindCommand {
+		return b.Command
+	}
+	return b.Target
+}
+
+// BookmarkContainer maps bookmark names to their bookmarks.
+type BookmarkContainer = map[string]Bookmark
 
-// BookmarkFileStore
-type BookmarkFileStore struct{}
+// BookmarkFileStore is a bookmark store backed by a json file.
+type BookmarkFileStore struct {
+	// Path is the path to the json file. If Path is empty the configured
+	// storePath is used.
+	Path string
+}
+
+// path returns the path to the json file backing the store.
+func (s BookmarkFileStore) path() string {
+	if s.Path != "" {
+		return s.Path
+	}
+	return viper.GetViper().GetString("storePath")
+}
+
+// ScriptDir implements the BookmarkStoreScripter interface.
+// The scripts are kept next to the json file in a directory named after
+// it, e.g. bookmarks.scripts for bookmarks.json.
+func (s BookmarkFileStore) ScriptDir() string {
+	return s.sibling(".scripts")
+}
+
+// HistoryPath implements the BookmarkStoreHistorian interface.
+// The history is kept next to the json file in a file named after it, e.g.
+// bookmarks.history for bookmarks.json.
+func (s BookmarkFileStore) HistoryPath() string {
+	return s.sibling(".history")
+}
+
+// sibling returns the path to the json file with its extension replaced by
+// ext.
+func (s BookmarkFileStore) sibling(ext string) string {
+	storePath := s.path()
+	if e := filepath.Ext(storePath); e != filepath.Base(storePath) {
+		storePath = strings.TrimSuffix(storePath, e)
+	}
+	return storePath + ext
+}
 
 // Load implements the BookmarkStoreLoader interface.
-// It loads the user's bookmarks from a json file.
+// It loads the user's bookmarks from a json file. Stores written in an
+// older format are migrated in memory and upgraded on disk by the next
+// Update.
 func (s BookmarkFileStore) Load() (BookmarkContainer, error) {
-	storePath := viper.GetViper().GetString("storePath")
+	storePath := s.path()
 	if _, err := os.Stat(storePath); errors.Is(err, os.ErrNotExist) {
 		return BookmarkContainer{}, nil
 	}
@@ -42,26 +227,193 @@ func (s BookmarkFileStore) Load() (BookmarkContainer, error) {
 	if err != nil {
 		return nil, err
 	}
-	var bc BookmarkContainer
-	err = json.Unmarshal(store, &bc)
+	store, _, err = Migrate(store)
+	if err != nil {
+		return nil, err
+	}
+	var sf storeFile
+	err = json.Unmarshal(store, &sf)
 	if err != nil {
 		return nil, err
 	}
-	return bc, nil
+	if sf.Bookmarks == nil {
+		sf.Bookmarks = BookmarkContainer{}
+	}
+	return sf.Bookmarks, nil
 }
 
 // Update implements the BookmarkStoreUpdater interface.
-// It writes the user's bookmarks to a json file.
+// It writes the user's bookmarks to a json file. If the existing file was
+// written in an older format a backup of it is kept.
 func (s BookmarkFileStore) Update(store BookmarkContainer) error {
-	storePath := viper.GetViper().GetString("storePath")
-	b, err := json.Marshal(store)
+	unlock, err := filelock.Lock(s.path())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return s.update(store)
+}
+
+// Modify implements the BookmarkStoreModifier interface.
+// The store file is locked from the bookmarks are loaded until the modified
+// bookmarks have been written, so concurrent modifications from other
+// processes are never lost.
+func (s BookmarkFileStore) Modify(fn func(BookmarkContainer) error) error {
+	unlock, err := filelock.Lock(s.path())
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(storePath, b, 0666)
+	defer unlock()
+	store, err := s.Load()
+	if err != nil {
+		return err
+	}
+	if err := fn(store); err != nil {
+		return err
+	}
+	return s.update(store)
+}
+
+// update writes the user's bookmarks to a json file. The caller must hold
+// the store lock.
+func (s BookmarkFileStore) update(store BookmarkContainer) error {
+	storePath := s.path()
+	if _, err := s.backup(); err != nil {
+		return err
+	}
+	b, err := json.Marshal(storeFile{Version: CurrentVersion, Bookmarks: store})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(storePath, b, 0644)
+}
+
+// Migrate upgrades the store file to CurrentVersion. If dryRun is true the
+// changes are reported but nothing is written.
+func (s BookmarkFileStore) Migrate(dryRun bool) (*MigrationResult, error) {
+	storePath := s.path()
+	unlock, err := filelock.Lock(storePath)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	data, err := os.ReadFile(storePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return &MigrationResult{From: CurrentVersion, To: CurrentVersion}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	migrated, result, err := Migrate(data)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun || len(result.Steps) == 0 {
+		return result, nil
+	}
+	result.Backup, err = s.backup()
+	if err != nil {
+		return nil, err
+	}
+	return result, writeFileAtomic(storePath, migrated, 0644)
+}
+
+// backup copies the store file to a version suffixed backup file if it was
+// written in an older format. It returns the path to the backup or an empty
+// string if no backup was needed.
+func (s BookmarkFileStore) backup() (string, error) {
+	storePath := s.path()
+	data, err := os.ReadFile(storePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	version, err := Version(data)
+	if err != nil {
+		return "", fmt.Errorf("refusing to overwrite invalid store %s: %w", storePath, err)
+	}
+	if version >= CurrentVersion {
+		return "", nil
+	}
+	info, err := os.Stat(storePath)
+	if err != nil {
+		return "", err
+	}
+	backupPath := fmt.Sprintf("%s.v%d.bak", storePath, version)
+	return backupPath, writeFileAtomic(backupPath, data, info.Mode().Perm())
+}
+
+// Move moves the json file to newPath together with the script directory
+// and the history files kept next to it. The store and the history are
+// locked while the files are moved. An existing json file at newPath is replaced, but the
+// store is not moved if one of the other files would replace an existing
+// one.
+func (s BookmarkFileStore) Move(newPath string) error {
+	storePath := s.path()
+	if filepath.Clean(storePath) == filepath.Clean(newPath) {
+		return nil
+	}
+	unlock, err := filelock.Lock(storePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	// The history is appended to without the store lock.
+	unlockHistory, err := filelock.Lock(s.HistoryPath())
+	if err != nil {
+		return err
+	}
+	defer unlockHistory()
+	dst := BookmarkFileStore{Path: newPath}
+	moves := [][2]string{{storePath, newPath}}
+	for _, ext := range s.siblingExts() {
+		from, to := s.sibling(ext), dst.sibling(ext)
+		if _, err := os.Lstat(from); errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if _, err := os.Lstat(to); err == nil {
+			return fmt.Errorf("unable to move the store, %s already exists", to)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		moves = append(moves, [2]string{from, to})
+	}
+	for i, m := range moves {
+		if err := os.Rename(m[0], m[1]); err != nil {
+			// Move the files that were moved already back, so the store
+			// stays usable at its old path.
+			for j := i - 1; j >= 0; j-- {
+				os.Rename(moves[j][1], moves[j][0])
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// siblingExts returns the extensions of the files kept next to the json
+// file, see sibling. The history is followed by its rotated files, e.g.
+// .history.1.
+func (s BookmarkFileStore) siblingExts() []string {
+	exts := []string{".scripts", ".history"}
+	rotated, _ := filepath.Glob(s.HistoryPath() + ".[0-9]*")
+	for _, path := range rotated {
+		exts = append(exts, strings.TrimPrefix(path, s.sibling("")))
+	}
+	return exts
 }
 
 // NewBookmarkFileStore initializes a new FileStore.
 func NewBookmarkFileStore() *BookmarkFileStore {
 	return &BookmarkFileStore{}
 }
+
+// storeFile is the on-disk representation of a bookmark store.
+type storeFile struct {
+	Version   int               `json:"version"`
+	Bookmarks BookmarkContainer `json:"bookmarks"`
+}
@@ -0,0 +1,165 @@
+package store_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/henrikac/bookmark/internal/store"
+)
+
+func TestBookmarkFileStoreLoadLegacyFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+	legacy := `{"hello":"echo \"Hello world\"","list":"ls -al"}`
+	if err := os.WriteFile(path, []byte(legacy), 0666); err != nil {
+		t.Fatal(err)
+	}
+	s := &store.BookmarkFileStore{Path: path}
+	bookmarks, err := s.Load()
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	expected := map[string]string{
+		"hello": "echo \"Hello world\"",
+		"list":  "ls -al",
+	}
+	if len(bookmarks) != len(expected) {
+		t.Fatalf("Expected %d bookmarks\nFound: %d", len(expected), len(bookmarks))
+	}
+	for name, command := range expected {
+		if bookmarks[name].Command != command {
+			t.Errorf("Expected: %s\nGot: %s", command, bookmarks[name].Command)
+		}
+	}
+}
+
+func TestBookmarkFileStoreUpdateUpgradesLegacyFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+	if err := os.WriteFile(path, []byte(`{"list":"ls"}`), 0666); err != nil {
+		t.Fatal(err)
+	}
+	s := &store.BookmarkFileStore{Path: path}
+	bookmarks, err := s.Load()
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	bookmarks["list"] = store.Bookmark{
+		Command:     "ls",
+		Description: "List files",
+		Tags:        []string{"fs"},
+	}
+	if err := s.Update(bookmarks); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw struct {
+		Version   int                    `json:"version"`
+		Bookmarks map[string]interface{} `json:"bookmarks"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if raw.Version != store.CurrentVersion {
+		t.Errorf("Expected version: %d\nGot: %d\n%s", store.CurrentVersion, raw.Version, data)
+	}
+	if _, err := os.Stat(path + ".v1.bak"); err != nil {
+		t.Errorf("Expected a backup of the legacy store: %s", err)
+	}
+	reloaded, err := s.Load()
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if got := reloaded["list"]; got.Command != "ls" || got.Description != "List files" || len(got.Tags) != 1 {
+		t.Errorf("Unexpected bookmark after reload: %+v", got)
+	}
+}
+
+func TestBookmarkFileStoreLoadMissingFile(t *testing.T) {
+	s := &store.BookmarkFileStore{Path: filepath.Join(t.TempDir(), "missing.json")}
+	bookmarks, err := s.Load()
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if len(bookmarks) != 0 {
+		t.Errorf("Expected no bookmarks\nFound: %d", len(bookmarks))
+	}
+}
+
+func TestBookmarkFileStoreSiblings(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{filepath.Join(dir, "bookmarks.json"), filepath.Join(dir, "bookmarks.scripts")},
+		{filepath.Join(dir, ".bookmarks"), filepath.Join(dir, ".bookmarks.scripts")},
+	}
+	for _, tt := range tests {
+		s := &store.BookmarkFileStore{Path: tt.path}
+		if got := s.ScriptDir(); got != tt.expected {
+			t.Errorf("Expected: %s\nGot: %s", tt.expected, got)
+		}
+		history := strings.TrimSuffix(tt.expected, ".scripts") + ".history"
+		if got := s.HistoryPath(); got != history {
+			t.Errorf("Expected: %s\nGot: %s", history, got)
+		}
+	}
+}
+
+func TestBookmarkFileStoreMove(t *testing.T) {
+	dir := t.TempDir()
+	s := store.BookmarkFileStore{Path: filepath.Join(dir, "bookmarks.json")}
+	if err := s.Update(store.BookmarkContainer{"deploy": {Kind: store.KindScript, Target: "deploy"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(s.ScriptDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(s.ScriptDir(), "deploy"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{s.HistoryPath(), s.HistoryPath() + ".1", s.HistoryPath() + ".2"} {
+		if err := os.WriteFile(path, []byte("{}\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	moved := store.BookmarkFileStore{Path: filepath.Join(dir, "new", "store.json")}
+	if err := os.Mkdir(filepath.Dir(moved.Path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Move(moved.Path); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	bookmarks, err := moved.Load()
+	if err != nil || bookmarks["deploy"].Target != "deploy" {
+		t.Errorf("Expected the bookmarks to be moved\nGot: %v %v", bookmarks, err)
+	}
+	for _, path := range []string{filepath.Join(moved.ScriptDir(), "deploy"), moved.HistoryPath(), moved.HistoryPath() + ".1", moved.HistoryPath() + ".2"} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected %s to be moved\nGot: %s", path, err)
+		}
+	}
+	for _, path := range []string{s.Path, s.ScriptDir(), s.HistoryPath(), s.HistoryPath() + ".1", s.HistoryPath() + ".2"} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be moved\nGot: %v", path, err)
+		}
+	}
+
+	if err := os.WriteFile(s.Path, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(s.ScriptDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Move(moved.Path); err == nil {
+		t.Error("Expected an error for an existing script directory")
+	}
+	if _, err := os.Stat(s.Path); err != nil {
+		t.Errorf("Expected the store to stay at its path\nGot: %s", err)
+	}
+}
@@ -0,0 +1,178 @@
+// Package tagexpr implements boolean expressions over bookmark tags.
+//
+// An expression combines tags with the operators && (and), || (or) and
+// ! (not), and may group them with parentheses, e.g. "k8s && !prod".
+// && binds tighter than ||.
+package tagexpr
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// An Expr is a parsed tag expression.
+type Expr interface {
+	// Match reports whether a bookmark with the given tags matches the
+	// expression.
+	Match(tags []string) bool
+}
+
+// A SyntaxError describes an invalid tag expression.
+type SyntaxError struct {
+	// Pos is the byte offset of the error in the expression.
+	Pos int
+	// Msg describes the error.
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid tag expression at position %d: %s", e.Pos, e.Msg)
+}
+
+var tagRe = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.:/-]*`)
+
+// Valid reports whether tag is a valid tag name. A tag name consists of
+// letters, digits and the characters _ . : / - and does not start with
+// one of . : / -.
+func Valid(tag string) bool {
+	return tagRe.FindString(tag) == tag && tag != ""
+}
+
+type tagExpr string
+
+func (e tagExpr) Match(tags []string) bool {
+	for _, t := range tags {
+		if t == string(e) {
+			return true
+		}
+	}
+	return false
+}
+
+type notExpr struct{ x Expr }
+
+func (e notExpr) Match(tags []string) bool { return !e.x.Match(tags) }
+
+type andExpr struct{ x, y Expr }
+
+func (e andExpr) Match(tags []string) bool { return e.x.Match(tags) && e.y.Match(tags) }
+
+type orExpr struct{ x, y Expr }
+
+func (e orExpr) Match(tags []string) bool { return e.x.Match(tags) || e.y.Match(tags) }
+
+// Parse parses the tag expression s.
+func Parse(s string) (Expr, error) {
+	p := &parser{s: s}
+	p.next()
+	x, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok != "" {
+		return nil, &SyntaxError{Pos: p.pos, Msg: fmt.Sprintf("unexpected %q", p.tok)}
+	}
+	return x, nil
+}
+
+// parser is a recursive descent parser of tag expressions.
+type parser struct {
+	s string
+	// off is the offset of the next token in s.
+	off int
+	// tok is the current token, which starts at pos. It is empty at the end
+	// of s.
+	tok string
+	pos int
+}
+
+// next advances to the next token.
+func (p *parser) next() {
+	for p.off < len(p.s) && (p.s[p.off] == ' ' || p.s[p.off] == '\t') {
+		p.off++
+	}
+	p.pos = p.off
+	rest := p.s[p.off:]
+	switch {
+	case rest == "":
+		p.tok = ""
+	case len(rest) >= 2 && (rest[:2] == "&&" || rest[:2] == "||"):
+		p.tok = rest[:2]
+	case rest[0] == '!' || rest[0] == '(' || rest[0] == ')':
+		p.tok = rest[:1]
+	default:
+		p.tok = tagRe.FindString(rest)
+		if p.tok == "" {
+			// An invalid character, which is reported by the caller.
+			p.tok = rest[:1]
+		}
+	}
+	p.off += len(p.tok)
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	x, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok == "||" {
+		p.next()
+		y, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		x = orExpr{x, y}
+	}
+	return x, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok == "&&" {
+		p.next()
+		y, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		x = andExpr{x, y}
+	}
+	return x, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	switch {
+	case p.tok == "!":
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{x}, nil
+	case p.tok == "(":
+		open := p.pos
+		p.next()
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok != ")" {
+			if p.tok == "" {
+				return nil, &SyntaxError{Pos: open, Msg: `unclosed "("`}
+			}
+			return nil, &SyntaxError{Pos: p.pos, Msg: fmt.Sprintf("unexpected %q", p.tok)}
+		}
+		p.next()
+		return x, nil
+	case Valid(p.tok):
+		x := tagExpr(p.tok)
+		p.next()
+		return x, nil
+	case p.tok == "":
+		return nil, &SyntaxError{Pos: p.pos, Msg: "missing tag"}
+	default:
+		return nil, &SyntaxError{Pos: p.pos, Msg: fmt.Sprintf("unexpected %q", p.tok)}
+	}
+}
@@ -0,0 +1,93 @@
+package tagexpr_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/henrikac/bookmark/internal/tagexpr"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		expr     string
+		tags     []string
+		expected bool
+	}{
+		{"k8s", []string{"k8s"}, true},
+		{"k8s", []string{"docker"}, false},
+		{"k8s", nil, false},
+		{"!k8s", nil, true},
+		{"k8s && !prod", []string{"k8s", "dev"}, true},
+		{"k8s && !prod", []string{"k8s", "prod"}, false},
+		{"k8s || docker", []string{"docker"}, true},
+		{"a || b && c", []string{"a"}, true},
+		{"(a || b) && c", []string{"a"}, false},
+		{"(a || b) && c", []string{"b", "c"}, true},
+		{"!!a", []string{"a"}, true},
+		{"!(a && b)", []string{"a", "b"}, false},
+		{"env:prod", []string{"env:prod"}, true},
+		{"  a&&b  ", []string{"a", "b"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := tagexpr.Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Error: %s", err)
+			}
+			if got := expr.Match(tt.tags); got != tt.expected {
+				t.Errorf("Expected: %t\nGot: %t", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		expr string
+		pos  int
+		msg  string
+	}{
+		{"", 0, "missing tag"},
+		{"a &&", 4, "missing tag"},
+		{"a b", 2, `unexpected "b"`},
+		{"(a || b", 0, `unclosed "("`},
+		{"a)", 1, `unexpected ")"`},
+		{"a & b", 2, `unexpected "&"`},
+		{"a && -b", 5, `unexpected "-"`},
+		{"(a b)", 3, `unexpected "b"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := tagexpr.Parse(tt.expr)
+			var syntaxErr *tagexpr.SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Expected a syntax error\nGot: %v", err)
+			}
+			if syntaxErr.Pos != tt.pos || syntaxErr.Msg != tt.msg {
+				t.Errorf("Expected: %d %s\nGot: %d %s", tt.pos, tt.msg, syntaxErr.Pos, syntaxErr.Msg)
+			}
+		})
+	}
+}
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		tag      string
+		expected bool
+	}{
+		{"k8s", true},
+		{"env:prod", true},
+		{"team/infra", true},
+		{"v1.2", true},
+		{"", false},
+		{"-prod", false},
+		{"two words", false},
+		{"a&&b", false},
+		{"!a", false},
+	}
+	for _, tt := range tests {
+		if got := tagexpr.Valid(tt.tag); got != tt.expected {
+			t.Errorf("Valid(%q) = %t, expected %t", tt.tag, got, tt.expected)
+		}
+	}
+}
@@ -16,6 +16,7 @@
 package main
 
 import (
+	"errors"
 	"os"
 
 	"github.com/henrikac/bookmark/cmd"
@@ -23,6 +24,10 @@ import (
 
 func main() {
 	if err := cmd.Execute(); err != nil {
+		var exitErr *cmd.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		os.Exit(1)
 	}
 }